@@ -0,0 +1,205 @@
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/vshn/espejo/api/v1beta1"
+)
+
+// AnnotationV1beta1Fields holds the fields of a v1beta1 SyncConfig that cannot be represented in v1alpha1, such as the
+// options of the sync items. It preserves the fields when a SyncConfig is read and written back as v1alpha1.
+const AnnotationV1beta1Fields = "sync.appuio.ch/v1beta1-fields"
+
+// v1beta1Fields is the content of AnnotationV1beta1Fields.
+type v1beta1Fields struct {
+	// Spec holds the spec fields that do not exist in v1alpha1.
+	Spec map[string]json.RawMessage `json:"spec,omitempty"`
+	// SyncItems holds the fields of the sync items besides their manifest.
+	SyncItems []itemFields `json:"syncItems,omitempty"`
+	// DeleteItems holds the fields of the delete items that do not exist in v1alpha1.
+	DeleteItems []itemFields `json:"deleteItems,omitempty"`
+}
+
+// itemFields holds the fields of a sync or delete item that has been identified by its apiVersion, kind and name.
+// The items are matched by identity instead of their position, so that the fields stay with their item if the items
+// are reordered, added or removed in v1alpha1.
+type itemFields struct {
+	APIVersion string                     `json:"apiVersion"`
+	Kind       string                     `json:"kind"`
+	Name       string                     `json:"name"`
+	Fields     map[string]json.RawMessage `json:"fields"`
+}
+
+// takeItemFields removes the fields of the first item with the given identity from the given list and returns them.
+// It returns nil if no item has the given identity.
+func takeItemFields(list *[]itemFields, apiVersion, kind, name string) map[string]json.RawMessage {
+	for i, item := range *list {
+		if item.APIVersion == apiVersion && item.Kind == kind && item.Name == name {
+			*list = append((*list)[:i:i], (*list)[i+1:]...)
+			return item.Fields
+		}
+	}
+	return nil
+}
+
+// ConvertTo converts this SyncConfig to the hub version v1beta1.
+func (src *SyncConfig) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*v1beta1.SyncConfig)
+	if !ok {
+		return fmt.Errorf("expected a v1beta1 SyncConfig but got %T", dstRaw)
+	}
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+
+	fields := v1beta1Fields{}
+	if raw, found := dst.Annotations[AnnotationV1beta1Fields]; found {
+		if err := json.Unmarshal([]byte(raw), &fields); err != nil {
+			return fmt.Errorf("invalid annotation %s: %w", AnnotationV1beta1Fields, err)
+		}
+		delete(dst.Annotations, AnnotationV1beta1Fields)
+		if len(dst.Annotations) == 0 {
+			dst.Annotations = nil
+		}
+	}
+	spec := src.Spec.DeepCopy()
+	spec.SyncItems = nil
+	if err := convertJSON(spec, &dst.Spec); err != nil {
+		return err
+	}
+	if len(fields.Spec) > 0 {
+		if err := convertJSON(fields.Spec, &dst.Spec); err != nil {
+			return err
+		}
+	}
+	// Fields of items that have been removed or renamed in v1alpha1 are left in the lists and dropped.
+	for i, item := range dst.Spec.DeleteItems {
+		if itemField := takeItemFields(&fields.DeleteItems, item.APIVersion, item.Kind, item.Name); itemField != nil {
+			if err := convertJSON(itemField, &dst.Spec.DeleteItems[i]); err != nil {
+				return err
+			}
+		}
+	}
+	for _, item := range src.Spec.SyncItems {
+		syncItem := v1beta1.SyncItem{}
+		if itemField := takeItemFields(&fields.SyncItems, item.GetAPIVersion(), item.GetKind(), item.GetName()); itemField != nil {
+			if err := convertJSON(itemField, &syncItem); err != nil {
+				return err
+			}
+		}
+		syncItem.Manifest = v1beta1.Manifest{Unstructured: *item.Unstructured.DeepCopy()}
+		dst.Spec.SyncItems = append(dst.Spec.SyncItems, syncItem)
+	}
+	return convertJSON(src.Status, &dst.Status)
+}
+
+// ConvertFrom converts the given SyncConfig of the hub version v1beta1 to this version.
+func (dst *SyncConfig) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*v1beta1.SyncConfig)
+	if !ok {
+		return fmt.Errorf("expected a v1beta1 SyncConfig but got %T", srcRaw)
+	}
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+
+	spec := src.Spec.DeepCopy()
+	spec.SyncItems = nil
+	if err := convertJSON(spec, &dst.Spec); err != nil {
+		return err
+	}
+	for _, item := range src.Spec.SyncItems {
+		dst.Spec.SyncItems = append(dst.Spec.SyncItems, Manifest{Unstructured: *item.Manifest.Unstructured.DeepCopy()})
+	}
+
+	fields, err := extraFields(spec, &dst.Spec, src.Spec.SyncItems)
+	if err != nil {
+		return err
+	}
+	if len(fields.Spec) > 0 || len(fields.SyncItems) > 0 || len(fields.DeleteItems) > 0 {
+		raw, err := json.Marshal(fields)
+		if err != nil {
+			return err
+		}
+		if dst.Annotations == nil {
+			dst.Annotations = map[string]string{}
+		}
+		dst.Annotations[AnnotationV1beta1Fields] = string(raw)
+	}
+	return convertJSON(src.Status, &dst.Status)
+}
+
+// extraFields returns the fields of the given v1beta1 spec, sync items and delete items that are lost in the given
+// converted spec.
+func extraFields(src *v1beta1.SyncConfigSpec, converted *SyncConfigSpec, items []v1beta1.SyncItem) (v1beta1Fields, error) {
+	fields := v1beta1Fields{}
+	srcFields := map[string]json.RawMessage{}
+	if err := convertJSON(src, &srcFields); err != nil {
+		return fields, err
+	}
+	convertedFields := map[string]json.RawMessage{}
+	if err := convertJSON(converted, &convertedFields); err != nil {
+		return fields, err
+	}
+	for name, value := range srcFields {
+		if _, found := convertedFields[name]; !found && name != "syncItems" {
+			if fields.Spec == nil {
+				fields.Spec = map[string]json.RawMessage{}
+			}
+			fields.Spec[name] = value
+		}
+	}
+
+	for _, item := range items {
+		itemField := map[string]json.RawMessage{}
+		if err := convertJSON(item, &itemField); err != nil {
+			return fields, err
+		}
+		delete(itemField, "manifest")
+		for name, value := range itemField {
+			// Empty structs are not omitted by the JSON encoding.
+			if string(value) == "{}" {
+				delete(itemField, name)
+			}
+		}
+		if len(itemField) > 0 {
+			fields.SyncItems = append(fields.SyncItems, itemFields{
+				APIVersion: item.Manifest.GetAPIVersion(),
+				Kind:       item.Manifest.GetKind(),
+				Name:       item.Manifest.GetName(),
+				Fields:     itemField,
+			})
+		}
+	}
+
+	for i, item := range src.DeleteItems {
+		itemField := map[string]json.RawMessage{}
+		if err := convertJSON(item, &itemField); err != nil {
+			return fields, err
+		}
+		convertedField := map[string]json.RawMessage{}
+		if err := convertJSON(converted.DeleteItems[i], &convertedField); err != nil {
+			return fields, err
+		}
+		for name := range convertedField {
+			delete(itemField, name)
+		}
+		if len(itemField) > 0 {
+			fields.DeleteItems = append(fields.DeleteItems, itemFields{
+				APIVersion: item.APIVersion,
+				Kind:       item.Kind,
+				Name:       item.Name,
+				Fields:     itemField,
+			})
+		}
+	}
+	return fields, nil
+}
+
+// convertJSON converts between the types of different versions that share the same JSON representation.
+func convertJSON(src, dst interface{}) error {
+	raw, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, dst)
+}
@@ -0,0 +1,113 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/vshn/espejo/api/v1beta1"
+)
+
+func manifest(name string) unstructured.Unstructured {
+	obj := unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("ConfigMap")
+	obj.SetName(name)
+	return obj
+}
+
+func Test_SyncConfig_ConvertTo(t *testing.T) {
+	src := &SyncConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "config", Annotations: map[string]string{"other": "value"}},
+		Spec: SyncConfigSpec{
+			ForceRecreate:     true,
+			NamespaceSelector: &NamespaceSelector{MatchNames: []string{"default"}},
+			SyncItems:         []Manifest{{Unstructured: manifest("first")}, {Unstructured: manifest("second")}},
+			DeleteItems:       []DeleteMeta{{APIVersion: "v1", Kind: "ConfigMap", Name: "old"}},
+		},
+		Status: SyncConfigStatus{SynchronizedItemCount: 2},
+	}
+	dst := &v1beta1.SyncConfig{}
+
+	require.NoError(t, src.ConvertTo(dst))
+
+	assert.Equal(t, src.ObjectMeta, dst.ObjectMeta)
+	assert.True(t, dst.Spec.ForceRecreate)
+	assert.Equal(t, []string{"default"}, dst.Spec.NamespaceSelector.MatchNames)
+	assert.Equal(t, []v1beta1.SyncItem{
+		{Manifest: v1beta1.Manifest{Unstructured: manifest("first")}},
+		{Manifest: v1beta1.Manifest{Unstructured: manifest("second")}},
+	}, dst.Spec.SyncItems)
+	assert.Equal(t, []v1beta1.DeleteMeta{{APIVersion: "v1", Kind: "ConfigMap", Name: "old"}}, dst.Spec.DeleteItems)
+	assert.Equal(t, int64(2), dst.Status.SynchronizedItemCount)
+}
+
+func Test_SyncConfig_ConvertFrom_GivenV1beta1Fields_ThenRoundTrip(t *testing.T) {
+	forceRecreate := true
+	hub := &v1beta1.SyncConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "config"},
+		Spec: v1beta1.SyncConfigSpec{
+			TemplateEngine: v1beta1.TemplateEngineGoTemplate,
+			SyncItems: []v1beta1.SyncItem{
+				{Manifest: v1beta1.Manifest{Unstructured: manifest("first")}},
+				{
+					Manifest: v1beta1.Manifest{Unstructured: manifest("second")},
+					Options:  v1beta1.SyncItemOptions{ForceRecreate: &forceRecreate, ConflictPolicy: v1beta1.ConflictPolicySkip},
+					When:     "namespaceObject.metadata.name != 'default'",
+				},
+			},
+			DeleteItems: []v1beta1.DeleteMeta{
+				{APIVersion: "v1", Kind: "ConfigMap", Name: "old"},
+				{
+					APIVersion:        "v1",
+					Kind:              "ConfigMap",
+					Name:              "restricted",
+					NamespaceSelector: &v1beta1.NamespaceSelector{IgnoreNames: []string{"default"}},
+				},
+			},
+		},
+	}
+	spoke := &SyncConfig{}
+
+	require.NoError(t, spoke.ConvertFrom(hub))
+	assert.Equal(t, []Manifest{{Unstructured: manifest("first")}, {Unstructured: manifest("second")}}, spoke.Spec.SyncItems)
+	assert.Len(t, spoke.Spec.DeleteItems, 2)
+	assert.Contains(t, spoke.Annotations, AnnotationV1beta1Fields)
+
+	result := &v1beta1.SyncConfig{}
+	require.NoError(t, spoke.ConvertTo(result))
+	assert.Equal(t, hub, result)
+}
+
+func Test_SyncConfig_ConvertTo_GivenChangedItems_ThenMatchItemFieldsByIdentity(t *testing.T) {
+	src := &SyncConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "config",
+			Annotations: map[string]string{AnnotationV1beta1Fields: `{
+"syncItems":[
+  {"apiVersion":"v1","kind":"ConfigMap","name":"removed","fields":{"when":"false"}},
+  {"apiVersion":"v1","kind":"ConfigMap","name":"second","fields":{"options":{"conflictPolicy":"Skip"}}}
+],
+"deleteItems":[
+  {"apiVersion":"v1","kind":"ConfigMap","name":"renamed","fields":{"namespaceSelector":{"matchNames":["default"]}}}
+]}`},
+		},
+		Spec: SyncConfigSpec{
+			SyncItems:   []Manifest{{Unstructured: manifest("inserted")}, {Unstructured: manifest("second")}},
+			DeleteItems: []DeleteMeta{{APIVersion: "v1", Kind: "ConfigMap", Name: "old"}},
+		},
+	}
+	dst := &v1beta1.SyncConfig{}
+
+	require.NoError(t, src.ConvertTo(dst))
+
+	assert.Nil(t, dst.Annotations)
+	assert.Equal(t, []v1beta1.SyncItem{
+		{Manifest: v1beta1.Manifest{Unstructured: manifest("inserted")}},
+		{Manifest: v1beta1.Manifest{Unstructured: manifest("second")}, Options: v1beta1.SyncItemOptions{ConflictPolicy: v1beta1.ConflictPolicySkip}},
+	}, dst.Spec.SyncItems)
+	assert.Equal(t, []v1beta1.DeleteMeta{{APIVersion: "v1", Kind: "ConfigMap", Name: "old"}}, dst.Spec.DeleteItems)
+}
@@ -0,0 +1,5 @@
+package v1beta1
+
+// Hub marks this type as a conversion hub.
+// All other versions of SyncConfig are converted to and from this version.
+func (*SyncConfig) Hub() {}
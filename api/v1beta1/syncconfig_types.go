@@ -0,0 +1,407 @@
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+type (
+	// Manifest is an unstructured kubernetes object with kube-builder validation and pruning settings applied.
+	Manifest struct {
+		// +kubebuilder:pruning:PreserveUnknownFields
+		// +kubebuilder:validation:EmbeddedResource
+		unstructured.Unstructured `json:",inline"`
+	}
+
+	// SyncItem defines an object to be synced to the targeted namespaces and how it is synced.
+	SyncItem struct {
+		// Manifest is the object to be synced.
+		Manifest Manifest `json:"manifest"`
+		// Options override the settings of the SyncConfig for this item.
+		Options SyncItemOptions `json:"options,omitempty"`
+		// When is a CEL expression that is evaluated against each targeted namespace, available as variable "namespaceObject".
+		// The item is only synced into namespaces for which it evaluates to true. If empty, the item is synced into all
+		// targeted namespaces.
+		When string `json:"when,omitempty"`
+		// NamespaceSelector narrows the namespaces targeted by the SyncConfig for this item.
+		// If it sets neither MatchNames nor LabelSelector, all targeted namespaces that are not ignored match.
+		NamespaceSelector *NamespaceSelector `json:"namespaceSelector,omitempty"`
+	}
+
+	// SyncItemOptions override the settings of the SyncConfig for a single sync item.
+	SyncItemOptions struct {
+		// ForceRecreate overrides .spec.forceRecreate for this item.
+		ForceRecreate *bool `json:"forceRecreate,omitempty"`
+		// ConflictPolicy overrides .spec.conflictPolicy for this item.
+		ConflictPolicy ConflictPolicy `json:"conflictPolicy,omitempty"`
+		// IgnoreDifferences lists fields of this item whose existing values are preserved, in addition to
+		// .spec.ignoreDifferences.
+		IgnoreDifferences []IgnoreDifference `json:"ignoreDifferences,omitempty"`
+	}
+
+	// SyncConfigSpec defines the desired state of SyncConfig
+	SyncConfigSpec struct {
+		// ForceRecreate defines if objects should be deleted and recreated if updates fails
+		ForceRecreate bool `json:"forceRecreate,omitempty"`
+		// ApplyStrategy defines how sync items are written to the targeted namespaces.
+		// "Update" (default) replaces the whole object with the rendered item.
+		// "ServerSideApply" applies the rendered item with a field manager unique to this SyncConfig, so that fields
+		// owned by other field managers are left untouched.
+		ApplyStrategy ApplyStrategy `json:"applyStrategy,omitempty"`
+		// ForceConflicts takes over the ownership of fields that are owned by other field managers when using the
+		// "ServerSideApply" strategy. Without it, items with conflicting fields are counted as failed.
+		ForceConflicts bool `json:"forceConflicts,omitempty"`
+		// NamespaceSelector defines which namespaces should be targeted
+		NamespaceSelector *NamespaceSelector `json:"namespaceSelector,omitempty"`
+
+		// SyncItems lists items to be synced to targeted namespaces
+		SyncItems []SyncItem `json:"syncItems,omitempty"`
+		// DeleteItems lists items to be deleted from targeted namespaces
+		DeleteItems []DeleteMeta `json:"deleteItems,omitempty"`
+		// Prune defines if objects that have been synced by this SyncConfig should be deleted from the targeted
+		// namespaces once they are no longer part of SyncItems. Only objects carrying the ownership labels of this
+		// SyncConfig are deleted.
+		Prune bool `json:"prune,omitempty"`
+		// CleanupUnmatchedNamespaces defines if objects that have been synced by this SyncConfig should be deleted from
+		// namespaces that are no longer targeted, e.g. because a label was removed or the namespace is ignored.
+		// Only objects carrying the ownership labels of this SyncConfig are deleted.
+		CleanupUnmatchedNamespaces bool `json:"cleanupUnmatchedNamespaces,omitempty"`
+		// IgnoreDifferences lists fields of synced objects whose existing values in the targeted namespaces are preserved
+		// when the objects are updated or recreated.
+		IgnoreDifferences []IgnoreDifference `json:"ignoreDifferences,omitempty"`
+		// ConflictPolicy defines how objects are handled that already exist in a targeted namespace without having been
+		// synced by espejo, i.e. objects without the ownership labels.
+		// "Adopt" (default) takes over the existing object.
+		// "Skip" leaves the existing object untouched and lists it in the status.
+		// "Fail" leaves the existing object untouched, counts the item as failed and lists it in the status.
+		ConflictPolicy ConflictPolicy `json:"conflictPolicy,omitempty"`
+		// Priority resolves conflicts with other SyncConfigs that sync the same object into the same namespace.
+		// The SyncConfig with the higher priority syncs the object, the SyncConfigs with lower priorities skip it.
+		// Among SyncConfigs with the same priority, the one first by namespace and name syncs the object.
+		// Conflicts are reported in the "Conflict" condition.
+		Priority int32 `json:"priority,omitempty"`
+		// DeletionPolicy defines what happens to the synced objects when the SyncConfig is deleted.
+		// "Orphan" (default) leaves the synced objects in the targeted namespaces.
+		// "Delete" deletes all objects synced by this SyncConfig from all namespaces before the SyncConfig is removed.
+		DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+		// DryRun reconciles the SyncConfig using server-side dry-run requests. No objects are created, changed or deleted,
+		// instead the changes that would be made are reported in the status.
+		DryRun bool `json:"dryRun,omitempty"`
+		// TemplateEngine defines how the sync items are rendered for a targeted namespace.
+		// "Placeholders" (default) replaces placeholders such as ${PROJECT_NAME}.
+		// "GoTemplate" renders each sync item serialized as YAML as a single Go template with the target namespace, the
+		// metadata of the SyncConfig and the parameters as data.
+		TemplateEngine TemplateEngine `json:"templateEngine,omitempty"`
+		// Template renders further objects to be synced to targeted namespaces, in addition to SyncItems.
+		// The rendered objects are synced the same way as sync items with default options.
+		Template *Template `json:"template,omitempty"`
+	}
+
+	// Template defines a program that renders the objects to be synced to a targeted namespace.
+	Template struct {
+		// Jsonnet is a Jsonnet snippet that evaluates to an array of objects for a targeted namespace.
+		// The target namespace, the metadata of the SyncConfig and the parameters are available as the external
+		// variables "namespace", "syncConfig" and "parameters".
+		Jsonnet string `json:"jsonnet,omitempty"`
+		// Libraries lists the names of ConfigMaps in the namespace of the SyncConfig whose entries can be imported by
+		// the Jsonnet snippet, either by key or as "<ConfigMap name>/<key>".
+		Libraries []string `json:"libraries,omitempty"`
+	}
+
+	// IgnoreDifference defines fields of synced objects whose existing values are preserved.
+	// The entry applies to all sync items matching APIVersion, Kind and Name. Set Kind and Name to target a single sync item.
+	IgnoreDifference struct {
+		// APIVersion limits the entry to sync items of this API version. Applies to all API versions if empty.
+		APIVersion string `json:"apiVersion,omitempty"`
+		// Kind limits the entry to sync items of this kind. Applies to all kinds if empty.
+		Kind string `json:"kind,omitempty"`
+		// Name limits the entry to sync items with this name after placeholders have been replaced. Applies to all names if empty.
+		Name string `json:"name,omitempty"`
+		// JSONPointers lists the fields to preserve as JSON pointers (RFC 6901), e.g. "/spec/replicas".
+		JSONPointers []string `json:"jsonPointers,omitempty"`
+		// JSONPaths lists the fields to preserve as JSONPath expressions, e.g. ".metadata.annotations['example.com/key']".
+		// Only child and index selectors are supported.
+		JSONPaths []string `json:"jsonPaths,omitempty"`
+	}
+
+	// TargetReference identifies an object in a targeted namespace
+	TargetReference struct {
+		// Namespace of the object
+		Namespace string `json:"namespace"`
+		// APIVersion of the object
+		APIVersion string `json:"apiVersion"`
+		// Kind of the object
+		Kind string `json:"kind"`
+		// Name of the object
+		Name string `json:"name"`
+	}
+
+	// TargetStatus holds the result of syncing or deleting an object in a targeted namespace
+	TargetStatus struct {
+		TargetReference `json:",inline"`
+		// Result of the last sync or deletion of the object
+		Result TargetResult `json:"result"`
+		// Message describes why the object failed or has been skipped
+		Message string `json:"message,omitempty"`
+		// LastTransitionTime is the last time the result of the object changed
+		LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+	}
+
+	// DryRunStatus holds the changes a reconciliation in dry-run mode would have made
+	DryRunStatus struct {
+		// WouldCreate holds the number of objects that would be created.
+		WouldCreate int64 `json:"wouldCreate"`
+		// WouldChange holds the number of objects that would be updated or recreated.
+		WouldChange int64 `json:"wouldChange"`
+		// WouldDelete holds the number of objects that would be deleted.
+		WouldDelete int64 `json:"wouldDelete"`
+		// Namespaces lists the changes per namespace, sorted by namespace. Namespaces without changes are not listed.
+		// The list is truncated if there are too many namespaces.
+		Namespaces []NamespaceDryRunStatus `json:"namespaces,omitempty"`
+		// OmittedNamespaceCount holds the number of namespaces with changes that have been omitted from Namespaces.
+		OmittedNamespaceCount int64 `json:"omittedNamespaceCount,omitempty"`
+	}
+
+	// NamespaceDryRunStatus holds the changes a reconciliation in dry-run mode would have made in a namespace
+	NamespaceDryRunStatus struct {
+		// Namespace the changes would be made in
+		Namespace string `json:"namespace"`
+		// WouldCreate holds the number of objects that would be created in the namespace.
+		WouldCreate int64 `json:"wouldCreate,omitempty"`
+		// WouldChange holds the number of objects that would be updated or recreated in the namespace.
+		WouldChange int64 `json:"wouldChange,omitempty"`
+		// WouldDelete holds the number of objects that would be deleted from the namespace.
+		WouldDelete int64 `json:"wouldDelete,omitempty"`
+	}
+
+	// ManagedKind defines a kind of objects that have been synced by a SyncConfig
+	ManagedKind struct {
+		// APIVersion of the synced objects
+		APIVersion string `json:"apiVersion"`
+		// Kind of the synced objects
+		Kind string `json:"kind"`
+	}
+
+	// DeleteMeta defines an object by name, kind and version
+	DeleteMeta struct {
+		// Name of the item to be deleted
+		Name string `json:"name,omitempty"`
+		// Kind of the item to be deleted
+		Kind string `json:"kind,omitempty"`
+		// APIVersion of the item to be deleted
+		APIVersion string `json:"apiVersion,omitempty"`
+		// NamespaceSelector narrows the namespaces targeted by the SyncConfig for this item.
+		// If it sets neither MatchNames nor LabelSelector, all targeted namespaces that are not ignored match.
+		NamespaceSelector *NamespaceSelector `json:"namespaceSelector,omitempty"`
+	}
+
+	// NamespaceSelector provides a way to specify targeted namespaces
+	NamespaceSelector struct {
+		// LabelSelector of namespaces to be targeted. Can be combined with MatchNames to include unlabelled namespaces.
+		LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"`
+		// MatchNames lists namespace names to be targeted. Each entry can be a Regex pattern.
+		// A namespace is included if at least one pattern matches.
+		// Invalid patterns will cause the sync to be cancelled and the status conditions will contain the error message.
+		MatchNames []string `json:"matchNames,omitempty"`
+		// IgnoreNames lists namespace names to be ignored. Each entry can be a Regex pattern and if they match
+		// the namespaces will be excluded from the sync even if matching in "matchNames" or via LabelSelector.
+		// A namespace is ignored if at least one pattern matches.
+		// Invalid patterns will cause the sync to be cancelled and the status conditions will contain the error message.
+		IgnoreNames []string `json:"ignoreNames,omitempty"`
+	}
+
+	// SyncConfigStatus defines the observed state of SyncConfig
+	SyncConfigStatus struct {
+		// ObservedGeneration is the generation of the SyncConfig the status has been computed from.
+		ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+		// Conditions contain the states of the SyncConfig. A SyncConfig is considered Ready when all items have been synced
+		// or deleted without errors. If some items failed, the SyncConfig is considered Degraded.
+		Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge"`
+		// SynchronizedItemCount holds the accumulated number of created or updated objects in the targeted namespaces.
+		SynchronizedItemCount int64 `json:"synchronizedItemCount"`
+		// DeletedItemCount holds the accumulated number of deleted objects from targeted namespaces. Inexisting items do not get counted.
+		DeletedItemCount int64 `json:"deletedItemCount"`
+		// FailedItemCount holds the accumulated number of objects that could not be created, updated or deleted. Inexisting items do not get counted.
+		FailedItemCount int64 `json:"failedItemCount"`
+		// SkippedItemCount holds the accumulated number of objects that have not been synced due to the conflict policy.
+		SkippedItemCount int64 `json:"skippedItemCount"`
+		// MatchedNamespaceCount holds the number of namespaces targeted by the SyncConfig.
+		MatchedNamespaceCount int64 `json:"matchedNamespaceCount"`
+		// Targets lists the results of the objects handled in the last reconciliation.
+		// Failed and skipped objects are listed first. The list is truncated if there are too many objects, successfully
+		// synced or deleted objects are only summarized by their counts in this case.
+		Targets []TargetStatus `json:"targets,omitempty"`
+		// OmittedTargetCount holds the number of objects that have been omitted from Targets.
+		OmittedTargetCount int64 `json:"omittedTargetCount,omitempty"`
+		// DryRun holds the changes the last reconciliation would have made, if it ran in dry-run mode.
+		DryRun *DryRunStatus `json:"dryRun,omitempty"`
+		// ManagedKinds lists the kinds of objects that have been synced by this SyncConfig and may still exist in the
+		// targeted namespaces. It is used to find objects to be pruned.
+		ManagedKinds []ManagedKind `json:"managedKinds,omitempty"`
+	}
+
+	// ApplyStrategy defines how sync items are written to the targeted namespaces.
+	// +kubebuilder:validation:Enum=Update;ServerSideApply
+	ApplyStrategy string
+
+	// DeletionPolicy defines what happens to the synced objects when the SyncConfig is deleted.
+	// +kubebuilder:validation:Enum=Orphan;Delete
+	DeletionPolicy string
+
+	// ConflictPolicy defines how existing objects without ownership labels are handled.
+	// +kubebuilder:validation:Enum=Adopt;Skip;Fail
+	ConflictPolicy string
+
+	// TemplateEngine defines how sync items are rendered for the targeted namespaces.
+	// +kubebuilder:validation:Enum=Placeholders;GoTemplate
+	TemplateEngine string
+
+	// TargetResult is the result of syncing or deleting an object.
+	// +kubebuilder:validation:Enum=Synced;Deleted;Failed;Skipped
+	TargetResult string
+
+	// ConditionType identifies the type of a condition. The type is unique in the Status field.
+	ConditionType string
+
+	// +kubebuilder:object:root=true
+	// +kubebuilder:subresource:status
+	// +kubebuilder:storageversion
+	// +kubebuilder:printcolumn:name="Namespaces",type=integer,JSONPath=`.status.matchedNamespaceCount`
+	// +kubebuilder:printcolumn:name="Synced",type=integer,JSONPath=`.status.synchronizedItemCount`
+	// +kubebuilder:printcolumn:name="Deleted",type=integer,JSONPath=`.status.deletedItemCount`
+	// +kubebuilder:printcolumn:name="Failed",type=integer,JSONPath=`.status.failedItemCount`
+	// +kubebuilder:printcolumn:name="Skipped",type=integer,JSONPath=`.status.skippedItemCount`,priority=1
+	// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+	// SyncConfig is the Schema for the syncconfigs API
+	SyncConfig struct {
+		metav1.TypeMeta   `json:",inline"`
+		metav1.ObjectMeta `json:"metadata,omitempty"`
+
+		Spec   SyncConfigSpec   `json:"spec,omitempty"`
+		Status SyncConfigStatus `json:"status,omitempty"`
+	}
+
+	// +kubebuilder:object:root=true
+
+	// SyncConfigList contains a list of SyncConfig
+	SyncConfigList struct {
+		metav1.TypeMeta `json:",inline"`
+		metav1.ListMeta `json:"metadata,omitempty"`
+		Items           []SyncConfig `json:"items"`
+	}
+)
+
+const (
+	// ApplyStrategyUpdate replaces the whole object with the rendered item.
+	ApplyStrategyUpdate ApplyStrategy = "Update"
+	// ApplyStrategyServerSideApply applies the rendered item using server-side apply.
+	ApplyStrategyServerSideApply ApplyStrategy = "ServerSideApply"
+
+	// ConditionConfigReady tracks if the SyncConfig has been successfully reconciled.
+	ConditionConfigReady ConditionType = "Ready"
+	// ConditionErrored is given when no objects could be synced or deleted and the failed object count is > 0 or
+	// any other reconciliation error.
+	ConditionErrored ConditionType = "Errored"
+	// ConditionConflict is given when other SyncConfigs sync the same objects into the same namespaces.
+	ConditionConflict ConditionType = "Conflict"
+	// ConditionInvalid is given when the the SyncConfig Spec contains invalid properties. SyncConfigs will not be
+	// reconciled.
+	ConditionInvalid ConditionType = "Invalid"
+	// ConditionProgressing is given while the SyncConfig is being reconciled and the desired state has not been reached yet.
+	ConditionProgressing ConditionType = "Progressing"
+	// ConditionDegraded is given when the SyncConfig is invalid or any objects could not be synced or deleted.
+	ConditionDegraded ConditionType = "Degraded"
+
+	// DeletionPolicyOrphan leaves the synced objects in place when the SyncConfig is deleted.
+	DeletionPolicyOrphan DeletionPolicy = "Orphan"
+	// DeletionPolicyDelete deletes the synced objects when the SyncConfig is deleted.
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+
+	// ConflictPolicyAdopt takes over existing objects without ownership labels.
+	ConflictPolicyAdopt ConflictPolicy = "Adopt"
+	// ConflictPolicySkip leaves existing objects without ownership labels untouched.
+	ConflictPolicySkip ConflictPolicy = "Skip"
+	// ConflictPolicyFail leaves existing objects without ownership labels untouched and counts them as failed.
+	ConflictPolicyFail ConflictPolicy = "Fail"
+
+	// TemplateEnginePlaceholders replaces placeholders in the string values of the sync items.
+	TemplateEnginePlaceholders TemplateEngine = "Placeholders"
+	// TemplateEngineGoTemplate renders the string values of the sync items as Go templates.
+	TemplateEngineGoTemplate TemplateEngine = "GoTemplate"
+
+	// TargetResultSynced is given if the object has been created or updated.
+	TargetResultSynced TargetResult = "Synced"
+	// TargetResultDeleted is given if the object has been deleted.
+	TargetResultDeleted TargetResult = "Deleted"
+	// TargetResultFailed is given if the object could not be synced or deleted.
+	TargetResultFailed TargetResult = "Failed"
+	// TargetResultSkipped is given if the object has not been synced due to the conflict policy or priority.
+	TargetResultSkipped TargetResult = "Skipped"
+
+	// FinalizerCleanup is added to SyncConfigs with DeletionPolicyDelete. It is removed once all synced objects are deleted.
+	FinalizerCleanup = "sync.appuio.ch/cleanup"
+
+	// LabelManagedBy is set on every object synced by espejo. Its value is always ManagedByEspejo.
+	LabelManagedBy = "sync.appuio.ch/managed-by"
+	// ManagedByEspejo is the value of the LabelManagedBy label.
+	ManagedByEspejo = "espejo"
+	// LabelOwnerUID holds the UID of the SyncConfig that synced the object.
+	LabelOwnerUID = "sync.appuio.ch/owner-uid"
+	// AnnotationOwnerNamespace holds the namespace of the SyncConfig that synced the object.
+	AnnotationOwnerNamespace = "sync.appuio.ch/owner-namespace"
+	// AnnotationOwnerName holds the name of the SyncConfig that synced the object.
+	AnnotationOwnerName = "sync.appuio.ch/owner-name"
+	// AnnotationManifestHash holds the SHA-256 hash of the rendered sync item the object was synced from.
+	// Fields ignored by IgnoreDifferences are not part of the hash.
+	AnnotationManifestHash = "sync.appuio.ch/manifest-hash"
+
+	// SyncReasonFailed is given when the sync generally failed.
+	SyncReasonFailed = "SynchronizationFailed"
+	// SyncReasonSucceeded is given when the sync succeeded without errors.
+	SyncReasonSucceeded = "SynchronizationSucceeded"
+	// SyncReasonFailedWithError is given when the sync failed with a particular error.
+	SyncReasonFailedWithError = "SynchronizationFailedWithError"
+	// SyncReasonConfigInvalid is given if the SyncConfig contains invalid spec.
+	SyncReasonConfigInvalid = "InvalidSyncConfigSpec"
+	// SyncReasonOverlappingTargets is given if other SyncConfigs sync the same objects into the same namespaces.
+	SyncReasonOverlappingTargets = "OverlappingTargets"
+	// SyncReasonNoOverlappingTargets is given if no other SyncConfigs sync the same objects into the same namespaces.
+	SyncReasonNoOverlappingTargets = "NoOverlappingTargets"
+	// SyncReasonDeleting is given while the synced objects of a deleted SyncConfig are being deleted.
+	SyncReasonDeleting = "DeletingSyncedObjects"
+	// SyncReasonRetrying is given when the reconciliation could not be completed and will be retried.
+	SyncReasonRetrying = "Retrying"
+	// SyncReasonReconciled is given when the reconciliation has been completed.
+	SyncReasonReconciled = "Reconciled"
+	// SyncReasonForbidden is given when objects could not be synced or deleted due to missing permissions.
+	SyncReasonForbidden = "Forbidden"
+	// SyncReasonInvalid is given when objects have been rejected as invalid or the SyncConfig Spec is invalid.
+	SyncReasonInvalid = "Invalid"
+	// SyncReasonNoKindMatch is given when the kind of objects is not served by the cluster.
+	SyncReasonNoKindMatch = "NoKindMatch"
+	// SyncReasonConflict is given when objects could not be synced due to conflicts with existing objects or field managers.
+	SyncReasonConflict = "Conflict"
+	// SyncReasonRenderFailed is given when sync items could not be rendered for their target namespaces.
+	SyncReasonRenderFailed = "RenderFailed"
+)
+
+func init() {
+	SchemeBuilder.Register(&SyncConfig{}, &SyncConfigList{})
+}
+
+// ToDeleteObj creates a k8s Unstructured object based on a DeleteMeta obj
+func (in *DeleteMeta) ToDeleteObj(namespace string) *unstructured.Unstructured {
+	deleteObj := &unstructured.Unstructured{}
+	deleteObj.SetAPIVersion(in.APIVersion)
+	deleteObj.SetKind(in.Kind)
+	deleteObj.SetName(in.Name)
+	deleteObj.SetNamespace(namespace)
+
+	return deleteObj
+}
+
+// String returns string(condition).
+func (in ConditionType) String() string {
+	return string(in)
+}
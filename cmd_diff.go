@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	flag "github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/vshn/espejo/controllers"
+)
+
+const (
+	// diffExitNoChanges, diffExitChanges and diffExitError are the exit codes of the diff command, the same as of `kubectl diff`.
+	diffExitNoChanges = 0
+	diffExitChanges   = 1
+	diffExitError     = 2
+)
+
+// diffCommand prints a unified diff between the objects rendered from the SyncConfigs in a file and the live objects
+// in the cluster of the current kubeconfig context.
+// It exits with diffExitChanges if there are differences, so that it can be used to gate pipelines.
+func diffCommand(args []string) int {
+	f := flag.NewFlagSet("diff", flag.ContinueOnError)
+	filename := f.StringP("filename", "f", "", "File containing the SyncConfigs to diff, '-' reads from stdin.")
+	namespace := f.StringP("namespace", "n", "", "Namespace of SyncConfigs that do not specify one. "+
+		"Defaults to the namespace of the current kubeconfig context.")
+	clusterName := f.String("cluster-name", "", "Value of the ${CLUSTER_NAME} placeholder.")
+	f.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: espejo diff -f syncconfig.yaml")
+		fmt.Fprint(os.Stderr, f.FlagUsages())
+	}
+	if err := f.Parse(args); err != nil {
+		return diffExitError
+	}
+	if *filename == "" {
+		f.Usage()
+		return diffExitError
+	}
+
+	configs, err := readSyncConfigs(*filename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return diffExitError
+	}
+	kubeconfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(clientcmd.NewDefaultClientConfigLoadingRules(), &clientcmd.ConfigOverrides{})
+	restConfig, err := kubeconfig.ClientConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return diffExitError
+	}
+	if *namespace == "" {
+		if *namespace, _, err = kubeconfig.Namespace(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return diffExitError
+		}
+	}
+	c, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return diffExitError
+	}
+
+	exitCode := diffExitNoChanges
+	for i := range configs {
+		cfg := &configs[i]
+		if cfg.Namespace == "" {
+			cfg.Namespace = *namespace
+		}
+		diffs, err := controllers.DiffSyncConfig(context.Background(), c, cfg, *clusterName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "SyncConfig %s/%s: %v\n", cfg.Namespace, cfg.Name, err)
+			return diffExitError
+		}
+		for _, diff := range diffs {
+			if err := writeDiff(os.Stdout, diff); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return diffExitError
+			}
+			exitCode = diffExitChanges
+		}
+	}
+	return exitCode
+}
+
+// writeDiff writes the given difference as unified diff of the YAML representations to the given writer.
+func writeDiff(w io.Writer, diff controllers.ObjectDiff) error {
+	obj := diff.Desired
+	if obj == nil {
+		obj = diff.Live
+	}
+	name := fmt.Sprintf("%s/%s/%s/%s", obj.GetAPIVersion(), obj.GetKind(), obj.GetNamespace(), obj.GetName())
+	live, err := diffLines(diff.Live)
+	if err != nil {
+		return err
+	}
+	desired, err := diffLines(diff.Desired)
+	if err != nil {
+		return err
+	}
+	return difflib.WriteUnifiedDiff(w, difflib.UnifiedDiff{
+		A:        live,
+		B:        desired,
+		FromFile: "live/" + name,
+		ToFile:   "desired/" + name,
+		Context:  3,
+	})
+}
+
+// diffLines returns the lines of the YAML representation of the given object without its managed fields.
+// A nil object has no lines.
+func diffLines(obj *unstructured.Unstructured) ([]string, error) {
+	if obj == nil {
+		return nil, nil
+	}
+	c := obj.DeepCopy()
+	c.SetManagedFields(nil)
+	out, err := yaml.Marshal(c.Object)
+	if err != nil {
+		return nil, err
+	}
+	return difflib.SplitLines(strings.TrimSuffix(string(out), "\n")), nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	syncv1alpha1 "github.com/vshn/espejo/api/v1alpha1"
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
+)
+
+// readDocuments decodes all documents of the given YAML or JSON file. Lists are expanded into their items.
+// The path "-" reads from stdin.
+func readDocuments(path string) ([]*unstructured.Unstructured, error) {
+	var reader io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	var docs []*unstructured.Unstructured
+	decoder := yaml.NewYAMLOrJSONDecoder(reader, 4096)
+	for {
+		obj := &unstructured.Unstructured{}
+		err := decoder.Decode(&obj.Object)
+		if errors.Is(err, io.EOF) {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not decode %s: %w", path, err)
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+		if !obj.IsList() {
+			docs = append(docs, obj)
+			continue
+		}
+		err = obj.EachListItem(func(item runtime.Object) error {
+			docs = append(docs, item.(*unstructured.Unstructured))
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not decode list in %s: %w", path, err)
+		}
+	}
+}
+
+// readSyncConfigs reads all SyncConfigs from the given YAML or JSON file. Documents of other kinds are ignored.
+// SyncConfigs of API version v1alpha1 are converted to v1beta1.
+func readSyncConfigs(path string) ([]syncv1beta1.SyncConfig, error) {
+	docs, err := readDocuments(path)
+	if err != nil {
+		return nil, err
+	}
+	var configs []syncv1beta1.SyncConfig
+	for _, doc := range docs {
+		cfg := syncv1beta1.SyncConfig{}
+		switch doc.GroupVersionKind() {
+		case syncv1beta1.GroupVersion.WithKind("SyncConfig"):
+			if err := fromUnstructured(doc, &cfg); err != nil {
+				return nil, fmt.Errorf("could not decode SyncConfig %q: %w", doc.GetName(), err)
+			}
+		case syncv1alpha1.GroupVersion.WithKind("SyncConfig"):
+			old := syncv1alpha1.SyncConfig{}
+			if err := fromUnstructured(doc, &old); err != nil {
+				return nil, fmt.Errorf("could not decode SyncConfig %q: %w", doc.GetName(), err)
+			}
+			if err := old.ConvertTo(&cfg); err != nil {
+				return nil, fmt.Errorf("could not convert SyncConfig %q: %w", doc.GetName(), err)
+			}
+			cfg.SetGroupVersionKind(syncv1beta1.GroupVersion.WithKind("SyncConfig"))
+		default:
+			continue
+		}
+		configs = append(configs, cfg)
+	}
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("%s does not contain any SyncConfig", path)
+	}
+	return configs, nil
+}
+
+// readNamespaces reads all Namespaces from the given YAML or JSON file, e.g. the output of `kubectl get namespaces -o yaml`.
+// Namespaces without a phase are considered active.
+func readNamespaces(path string) ([]corev1.Namespace, error) {
+	docs, err := readDocuments(path)
+	if err != nil {
+		return nil, err
+	}
+	var namespaces []corev1.Namespace
+	for _, doc := range docs {
+		if doc.GroupVersionKind() != corev1.SchemeGroupVersion.WithKind("Namespace") {
+			continue
+		}
+		ns := corev1.Namespace{}
+		if err := fromUnstructured(doc, &ns); err != nil {
+			return nil, fmt.Errorf("could not decode Namespace %q: %w", doc.GetName(), err)
+		}
+		if ns.Status.Phase == "" {
+			ns.Status.Phase = corev1.NamespaceActive
+		}
+		namespaces = append(namespaces, ns)
+	}
+	return namespaces, nil
+}
+
+// readConfigMaps reads all ConfigMaps from the given YAML or JSON file. Documents of other kinds are ignored.
+func readConfigMaps(path string) ([]corev1.ConfigMap, error) {
+	docs, err := readDocuments(path)
+	if err != nil {
+		return nil, err
+	}
+	var configMaps []corev1.ConfigMap
+	for _, doc := range docs {
+		if doc.GroupVersionKind() != corev1.SchemeGroupVersion.WithKind("ConfigMap") {
+			continue
+		}
+		configMap := corev1.ConfigMap{}
+		if err := fromUnstructured(doc, &configMap); err != nil {
+			return nil, fmt.Errorf("could not decode ConfigMap %q: %w", doc.GetName(), err)
+		}
+		configMaps = append(configMaps, configMap)
+	}
+	return configMaps, nil
+}
+
+// fromUnstructured converts the given object into the given typed object using its JSON representation.
+func fromUnstructured(obj *unstructured.Unstructured, into interface{}) error {
+	data, err := obj.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, into)
+}
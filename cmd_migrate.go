@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	flag "github.com/spf13/pflag"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
+)
+
+// migrateStorageCommand rewrites all SyncConfigs in the cluster of the current kubeconfig context, so that they are
+// stored in the storage version v1beta1, and then removes v1alpha1 from the stored versions of the CRD.
+// The conversion webhook must be enabled for this.
+func migrateStorageCommand(args []string) int {
+	f := flag.NewFlagSet("migrate-storage", flag.ContinueOnError)
+	f.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: espejo migrate-storage")
+		fmt.Fprint(os.Stderr, f.FlagUsages())
+	}
+	if err := f.Parse(args); err != nil {
+		return 1
+	}
+
+	kubeconfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(clientcmd.NewDefaultClientConfigLoadingRules(), &clientcmd.ConfigOverrides{})
+	restConfig, err := kubeconfig.ClientConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	c, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := migrateStorage(context.Background(), c); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// migrateStorage writes every SyncConfig back unchanged, which makes the API server store it in the current storage
+// version, and sets the stored versions of the CRD to the storage version afterwards.
+func migrateStorage(ctx context.Context, c client.Client) error {
+	list := &syncv1beta1.SyncConfigList{}
+	if err := c.List(ctx, list); err != nil {
+		return err
+	}
+	for _, item := range list.Items {
+		key := client.ObjectKeyFromObject(&item)
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			cfg := &syncv1beta1.SyncConfig{}
+			if err := c.Get(ctx, key, cfg); err != nil {
+				return client.IgnoreNotFound(err)
+			}
+			return c.Update(ctx, cfg)
+		})
+		if err != nil {
+			return fmt.Errorf("could not migrate SyncConfig %s: %w", key, err)
+		}
+		fmt.Printf("migrated SyncConfig %s\n", key)
+	}
+
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	if err := c.Get(ctx, client.ObjectKey{Name: "syncconfigs." + syncv1beta1.GroupVersion.Group}, crd); err != nil {
+		return err
+	}
+	patch := client.MergeFrom(crd.DeepCopy())
+	crd.Status.StoredVersions = []string{syncv1beta1.GroupVersion.Version}
+	if err := c.Status().Patch(ctx, crd, patch); err != nil {
+		return fmt.Errorf("could not update stored versions of CRD %s: %w", crd.Name, err)
+	}
+	fmt.Printf("set stored versions of CRD %s to %s\n", crd.Name, syncv1beta1.GroupVersion.Version)
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	flag "github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
+	"github.com/vshn/espejo/controllers"
+)
+
+// renderCommand renders the sync items of the SyncConfigs in a file for the namespaces in another file and prints them
+// as multi-document YAML. It does not require a connection to a cluster.
+func renderCommand(args []string) int {
+	f := flag.NewFlagSet("render", flag.ContinueOnError)
+	filename := f.StringP("filename", "f", "", "File containing the SyncConfigs to render, '-' reads from stdin.")
+	namespacesFile := f.String("namespaces", "", "File containing the Namespaces to render the SyncConfigs for, "+
+		"e.g. the output of 'kubectl get namespaces -o yaml'.")
+	clusterName := f.String("cluster-name", "", "Value of the ${CLUSTER_NAME} placeholder.")
+	librariesFile := f.String("libraries", "", "File containing the ConfigMaps that provide the libraries of the Jsonnet templates.")
+	f.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: espejo render -f syncconfig.yaml --namespaces namespaces.yaml")
+		fmt.Fprint(os.Stderr, f.FlagUsages())
+	}
+	if err := f.Parse(args); err != nil {
+		return 1
+	}
+	if *filename == "" || *namespacesFile == "" {
+		f.Usage()
+		return 1
+	}
+
+	configs, err := readSyncConfigs(*filename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	namespaces, err := readNamespaces(*namespacesFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	var libraries []corev1.ConfigMap
+	if *librariesFile != "" {
+		if libraries, err = readConfigMaps(*librariesFile); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	failed := false
+	for i := range configs {
+		if err := renderSyncConfig(os.Stdout, &configs[i], namespaces, *clusterName, libraries); err != nil {
+			fmt.Fprintf(os.Stderr, "SyncConfig %s: %v\n", configs[i].Name, err)
+			failed = true
+		}
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// renderSyncConfig writes the rendered sync items of the given SyncConfig as YAML documents to the given writer.
+// Items that cannot be rendered are left out and returned as error.
+func renderSyncConfig(w io.Writer, cfg *syncv1beta1.SyncConfig, namespaces []corev1.Namespace, clusterName string, libraries []corev1.ConfigMap) error {
+	rendered, renderErr := controllers.RenderSyncConfig(cfg, namespaces, clusterName, libraries)
+	for _, ns := range rendered {
+		for _, item := range ns.Items {
+			out, err := yaml.Marshal(item.Object)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "---\n# Source: SyncConfig %s, namespace %s\n%s", cfg.Name, ns.Namespace, out)
+		}
+	}
+	return renderErr
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/vshn/espejo/controllers"
+)
+
+type (
+	// validationResult is the result of validating a SyncConfig.
+	validationResult struct {
+		SyncConfig string                          `json:"syncConfig"`
+		Valid      bool                            `json:"valid"`
+		Findings   []controllers.ValidationFinding `json:"findings"`
+	}
+)
+
+// validateCommand validates the SyncConfigs in a file without a cluster and prints the problems found, either
+// human-readable or as JSON. It exits with 1 if any SyncConfig is invalid.
+func validateCommand(args []string) int {
+	f := flag.NewFlagSet("validate", flag.ContinueOnError)
+	filename := f.StringP("filename", "f", "", "File containing the SyncConfigs to validate, '-' reads from stdin.")
+	output := f.StringP("output", "o", "text", "Output format, one of 'text' or 'json'.")
+	f.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: espejo validate -f syncconfig.yaml [-o json]")
+		fmt.Fprint(os.Stderr, f.FlagUsages())
+	}
+	if err := f.Parse(args); err != nil {
+		return 1
+	}
+	if *filename == "" || (*output != "text" && *output != "json") {
+		f.Usage()
+		return 1
+	}
+
+	configs, err := readSyncConfigs(*filename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	results := make([]validationResult, 0, len(configs))
+	valid := true
+	for i := range configs {
+		findings := controllers.ValidateSyncConfig(&configs[i], scheme)
+		results = append(results, validationResult{SyncConfig: configs[i].Name, Valid: len(findings) == 0, Findings: findings})
+		valid = valid && len(findings) == 0
+	}
+
+	if *output == "json" {
+		err = writeValidationJSON(os.Stdout, results)
+	} else {
+		err = writeValidationText(os.Stdout, results)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if !valid {
+		return 1
+	}
+	return 0
+}
+
+// writeValidationJSON writes the given results as indented JSON array to the given writer.
+func writeValidationJSON(w io.Writer, results []validationResult) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(results)
+}
+
+// writeValidationText writes one line per finding and a summary to the given writer.
+func writeValidationText(w io.Writer, results []validationResult) error {
+	validCount := 0
+	for _, result := range results {
+		if result.Valid {
+			validCount++
+		}
+		for _, finding := range result.Findings {
+			if _, err := fmt.Fprintf(w, "%s: %s: %s\n", result.SyncConfig, finding.Field, finding.Message); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d of %d SyncConfigs are valid\n", validCount, len(results))
+	return err
+}
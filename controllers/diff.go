@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
+)
+
+// ObjectDiff is a difference between an object rendered from a SyncConfig and the live object in the cluster.
+type ObjectDiff struct {
+	// Desired is the object as it would be written by a reconciliation, nil if the object would be deleted.
+	Desired *unstructured.Unstructured
+	// Live is the existing object, nil if the object does not exist yet.
+	Live *unstructured.Unstructured
+}
+
+// DiffSyncConfig renders the given SyncConfig for all targeted active namespaces of the cluster and returns the
+// objects that would be created, changed or deleted by a reconciliation.
+// With the apply strategy "Update", the rendered objects are merged into the live objects with copyInto, the same way
+// updates do, so that system managed fields do not show up as differences. With "ServerSideApply", the rendered
+// objects are applied with a server-side dry-run request and the result is compared to the live objects.
+// Objects that would be skipped because of the conflict policy or an overlapping SyncConfig with a higher priority
+// are not included, neither are objects that would be pruned.
+// If the SyncConfig already exists in the cluster, its UID is used for the ownership metadata.
+// The given cluster name is the value of the ${CLUSTER_NAME} placeholder.
+func DiffSyncConfig(ctx context.Context, c client.Client, cfg *syncv1beta1.SyncConfig, clusterName string) ([]ObjectDiff, error) {
+	if cfg.UID == "" {
+		existing := &syncv1beta1.SyncConfig{}
+		err := c.Get(ctx, client.ObjectKeyFromObject(cfg), existing)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		cfg = cfg.DeepCopy()
+		cfg.UID = existing.UID
+	}
+	rc := &ReconciliationContext{ctx: ctx, cfg: cfg, clusterName: clusterName}
+	if err := rc.validateSpec(); err != nil {
+		return nil, err
+	}
+	libraries, err := fetchLibraries(ctx, c, cfg)
+	if err != nil {
+		return nil, err
+	}
+	rc.setLibraries(libraries)
+	namespaceList := &corev1.NamespaceList{}
+	if err := c.List(ctx, namespaceList); err != nil {
+		return nil, err
+	}
+	namespaces := make([]corev1.Namespace, 0)
+	for _, ns := range rc.filterNamespaces(namespaceList.Items) {
+		if ns.Status.Phase == corev1.NamespaceActive {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	r := &SyncConfigReconciler{Client: c}
+	if err := r.detectOverlaps(rc, namespaces); err != nil {
+		return nil, err
+	}
+
+	diffs := make([]ObjectDiff, 0)
+	for _, ns := range namespaces {
+		for i, deleteItem := range cfg.Spec.DeleteItems {
+			if !rc.isDeleteSelected(i, ns) {
+				continue
+			}
+			live, err := getLiveObject(ctx, c, deleteItem.ToDeleteObj(ns.Name))
+			if err != nil {
+				return nil, err
+			}
+			if live != nil {
+				diffs = append(diffs, ObjectDiff{Live: live})
+			}
+		}
+		for i, item := range cfg.Spec.SyncItems {
+			selected, err := rc.isSelected(i, ns)
+			if err == nil && !selected {
+				continue
+			}
+			obj, renderErr := rc.renderItem(item, ns)
+			if err == nil {
+				err = renderErr
+			}
+			if err != nil {
+				return nil, fmt.Errorf("namespace %s: %s %s: %w", ns.Name, obj.GetKind(), obj.GetName(), err)
+			}
+			diff, err := r.diffItem(rc, item.Options, obj)
+			if err != nil {
+				return nil, err
+			}
+			if diff != nil {
+				diffs = append(diffs, *diff)
+			}
+		}
+		objs, err := rc.renderTemplate(ns)
+		if err != nil {
+			return nil, fmt.Errorf("namespace %s: template: %w", ns.Name, err)
+		}
+		for _, obj := range objs {
+			diff, err := r.diffItem(rc, syncv1beta1.SyncItemOptions{}, obj)
+			if err != nil {
+				return nil, err
+			}
+			if diff != nil {
+				diffs = append(diffs, *diff)
+			}
+		}
+	}
+	return diffs, nil
+}
+
+// diffItem returns the difference between the given rendered object and its live object, or nil if the object would
+// not be changed or would be skipped.
+func (r *SyncConfigReconciler) diffItem(rc *ReconciliationContext, options syncv1beta1.SyncItemOptions, obj *unstructured.Unstructured) (*ObjectDiff, error) {
+	ignored := rc.ignoredFields(options)
+	if err := setOwnershipMetadata(rc.cfg, obj, ignored); err != nil {
+		return nil, err
+	}
+	err := rc.checkOverlap(obj)
+	if err == nil {
+		err = r.checkConflictPolicy(rc, rc.conflictPolicy(options), obj)
+	}
+	if errors.Is(err, errItemSkipped) || errors.Is(err, errItemConflict) {
+		// The object is left untouched by a reconciliation.
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	live, err := getLiveObject(rc.ctx, r.Client, obj)
+	if err != nil {
+		return nil, err
+	}
+	if rc.cfg.Spec.ApplyStrategy == syncv1beta1.ApplyStrategyServerSideApply {
+		desired := obj.DeepCopy()
+		removeIgnoredFields(ignored, desired)
+		if err := serverSideApply(rc.ctx, client.NewDryRunClient(r.Client), rc.cfg, desired); err != nil {
+			return nil, fmt.Errorf("%s %s/%s: %w", obj.GetKind(), obj.GetNamespace(), obj.GetName(), err)
+		}
+		if live == nil {
+			return &ObjectDiff{Desired: desired}, nil
+		}
+		if hasDrifted(live, desired) {
+			return &ObjectDiff{Desired: desired, Live: live}, nil
+		}
+		return nil, nil
+	}
+
+	if live == nil {
+		return &ObjectDiff{Desired: obj}, nil
+	}
+	preserveIgnoredFields(ignored, obj, live)
+	desired := live.DeepCopy()
+	copyInto(desired, obj)
+	if _, found := obj.Object["status"]; !found {
+		// Updates do not change the status, so keep it out of the diff.
+		unstructured.RemoveNestedField(desired.Object, "status")
+		if status, found := live.Object["status"]; found {
+			desired.Object["status"] = status
+		}
+	}
+	if hasDrifted(live, desired) {
+		return &ObjectDiff{Desired: desired, Live: live}, nil
+	}
+	return nil, nil
+}
+
+// getLiveObject returns the existing object of the given object or nil if it does not exist.
+func getLiveObject(ctx context.Context, c client.Client, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(obj.GroupVersionKind())
+	err := c.Get(ctx, client.ObjectKeyFromObject(obj), live)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return live, nil
+}
@@ -25,7 +25,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	// +kubebuilder:scaffold:imports
 
-	"github.com/vshn/espejo/api/v1alpha1"
+	"github.com/vshn/espejo/api/v1beta1"
 )
 
 type EnvTestSuite struct {
@@ -73,7 +73,7 @@ func registerCRDs(ts *EnvTestSuite) {
 	ts.Require().NoError(scheme.AddToScheme(ts.Scheme))
 	ts.Require().NoError(metav1.AddMetaToScheme(ts.Scheme))
 	ts.Require().NoError(corev1.AddToScheme(ts.Scheme))
-	ts.Require().NoError(v1alpha1.AddToScheme(ts.Scheme))
+	ts.Require().NoError(v1beta1.AddToScheme(ts.Scheme))
 
 	// +kubebuilder:scaffold:scheme
 }
@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/flowcontrol"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
+)
+
+const (
+	// EventReasonSynced is the reason of events about created and updated objects.
+	EventReasonSynced = "Synced"
+	// EventReasonRecreated is the reason of events about force recreated objects.
+	EventReasonRecreated = "Recreated"
+	// EventReasonDeleted is the reason of events about deleted objects.
+	EventReasonDeleted = "Deleted"
+	// EventReasonFailed is the reason of events about objects that could not be synced or deleted.
+	EventReasonFailed = "Failed"
+	// EventReasonDryRun is the reason of events about the changes a reconciliation in dry-run mode would have made.
+	EventReasonDryRun = "DryRun"
+
+	// maxFailureEvents is the maximum number of failed objects reported with an individual event on a SyncConfig per reconciliation.
+	maxFailureEvents = 5
+
+	// operationResultRecreated is the result of an object that has been force recreated.
+	operationResultRecreated controllerutil.OperationResult = "recreated"
+	// operationResultDeleted is the result of an object that has been deleted.
+	operationResultDeleted controllerutil.OperationResult = "deleted"
+)
+
+type (
+	// changeSummary counts the objects changed in a reconciliation.
+	changeSummary struct {
+		created    int64
+		updated    int64
+		recreated  int64
+		deleted    int64
+		namespaces map[string]*namespaceChanges
+	}
+
+	// namespaceChanges counts the objects changed in a namespace.
+	namespaceChanges struct {
+		created int64
+		changed int64
+		deleted int64
+	}
+)
+
+// rateLimitedRecorder drops events once its rate limit is exceeded.
+type rateLimitedRecorder struct {
+	record.EventRecorder
+	limiter flowcontrol.RateLimiter
+}
+
+// NewRateLimitedRecorder returns an EventRecorder that emits at most qps events per second with the given burst
+// and drops all other events.
+func NewRateLimitedRecorder(recorder record.EventRecorder, qps float32, burst int) record.EventRecorder {
+	return &rateLimitedRecorder{
+		EventRecorder: recorder,
+		limiter:       flowcontrol.NewTokenBucketRateLimiter(qps, burst),
+	}
+}
+
+// Event emits the given event, unless the rate limit is exceeded.
+func (r *rateLimitedRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	if r.limiter.TryAccept() {
+		r.EventRecorder.Event(object, eventtype, reason, message)
+	}
+}
+
+// Eventf emits the given event, unless the rate limit is exceeded.
+func (r *rateLimitedRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	if r.limiter.TryAccept() {
+		r.EventRecorder.Eventf(object, eventtype, reason, messageFmt, args...)
+	}
+}
+
+// AnnotatedEventf emits the given event, unless the rate limit is exceeded.
+func (r *rateLimitedRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	if r.limiter.TryAccept() {
+		r.EventRecorder.AnnotatedEventf(object, annotations, eventtype, reason, messageFmt, args...)
+	}
+}
+
+// recordChange counts the given change of the object and emits an event in the namespace of the object.
+// Unchanged objects are ignored.
+func (r *SyncConfigReconciler) recordChange(rc *ReconciliationContext, obj *unstructured.Unstructured, op controllerutil.OperationResult) {
+	if op == controllerutil.OperationResultNone {
+		return
+	}
+	if rc.changes.namespaces == nil {
+		rc.changes.namespaces = map[string]*namespaceChanges{}
+	}
+	ns := rc.changes.namespaces[obj.GetNamespace()]
+	if ns == nil {
+		ns = &namespaceChanges{}
+		rc.changes.namespaces[obj.GetNamespace()] = ns
+	}
+
+	var reason, action string
+	switch op {
+	case controllerutil.OperationResultCreated:
+		rc.changes.created++
+		ns.created++
+		reason, action = EventReasonSynced, "Created"
+	case controllerutil.OperationResultUpdated:
+		rc.changes.updated++
+		ns.changed++
+		reason, action = EventReasonSynced, "Updated"
+	case operationResultRecreated:
+		rc.changes.recreated++
+		ns.changed++
+		reason, action = EventReasonRecreated, "Force recreated"
+	case operationResultDeleted:
+		rc.changes.deleted++
+		ns.deleted++
+		reason, action = EventReasonDeleted, "Deleted"
+	}
+	r.recordObjectEvent(rc, obj, corev1.EventTypeNormal, reason,
+		fmt.Sprintf("%s by SyncConfig %s/%s", action, rc.cfg.Namespace, rc.cfg.Name))
+}
+
+// recordObjectEvent emits an event about the given synced object in its namespace, if namespace events are enabled.
+// Dry runs do not emit events in the namespaces.
+func (r *SyncConfigReconciler) recordObjectEvent(rc *ReconciliationContext, obj *unstructured.Unstructured, eventtype, reason, message string) {
+	if r.NamespaceRecorder == nil || rc.dryRun {
+		return
+	}
+	r.NamespaceRecorder.Event(obj, eventtype, reason, message)
+}
+
+// recordEvents emits events on the SyncConfig summarizing the changes and failures of the reconciliation.
+// Nothing is emitted if nothing changed and nothing failed, so that periodic reconciliations do not create events.
+// Failed objects are reported individually up to maxFailureEvents, the remaining failures are summarized in one event.
+func (r *SyncConfigReconciler) recordEvents(rc *ReconciliationContext) {
+	if r.Recorder == nil {
+		return
+	}
+	changes := rc.changes
+	switch {
+	case rc.dryRun:
+		if len(changes.namespaces) > 0 {
+			r.Recorder.Eventf(rc.cfg, corev1.EventTypeNormal, EventReasonDryRun,
+				"Would create %d, change %d and delete %d objects in %d namespaces",
+				changes.created, changes.updated+changes.recreated, changes.deleted, len(changes.namespaces))
+		}
+	default:
+		if changes.created > 0 || changes.updated > 0 {
+			r.Recorder.Eventf(rc.cfg, corev1.EventTypeNormal, EventReasonSynced,
+				"Created %d and updated %d objects in %d namespaces", changes.created, changes.updated, len(changes.namespaces))
+		}
+		if changes.recreated > 0 {
+			r.Recorder.Eventf(rc.cfg, corev1.EventTypeNormal, EventReasonRecreated, "Force recreated %d objects", changes.recreated)
+		}
+		if changes.deleted > 0 {
+			r.Recorder.Eventf(rc.cfg, corev1.EventTypeNormal, EventReasonDeleted, "Deleted %d objects", changes.deleted)
+		}
+	}
+
+	var reported int64
+	for _, target := range rc.targets[syncv1beta1.TargetResultFailed] {
+		if reported >= maxFailureEvents {
+			break
+		}
+		r.Recorder.Eventf(rc.cfg, corev1.EventTypeWarning, EventReasonFailed,
+			"%s %s/%s: %s", target.Kind, target.Namespace, target.Name, target.Message)
+		reported++
+	}
+	if remaining := rc.failCount - reported; remaining > 0 {
+		r.Recorder.Eventf(rc.cfg, corev1.EventTypeWarning, EventReasonFailed,
+			"%d more objects could not be synced or deleted, see .status.targets", remaining)
+	}
+}
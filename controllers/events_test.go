@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
+)
+
+func Test_SyncConfigReconciler_RecordEvents(t *testing.T) {
+	tests := map[string]struct {
+		changes                 []controllerutil.OperationResult
+		failures                int
+		expectedEvents          []string
+		expectedNamespaceEvents int
+	}{
+		"GivenNoChanges_ThenEmitNothing": {
+			changes: []controllerutil.OperationResult{controllerutil.OperationResultNone},
+		},
+		"GivenChanges_ThenSummarize": {
+			changes: []controllerutil.OperationResult{
+				controllerutil.OperationResultCreated,
+				controllerutil.OperationResultUpdated,
+				operationResultRecreated,
+				operationResultDeleted,
+			},
+			expectedEvents: []string{
+				"Normal Synced Created 1 and updated 1 objects in 1 namespaces",
+				"Normal Recreated Force recreated 1 objects",
+				"Normal Deleted Deleted 1 objects",
+			},
+			expectedNamespaceEvents: 4,
+		},
+		"GivenTooManyFailures_ThenSummarizeRemainingFailures": {
+			failures: maxFailureEvents + 2,
+			expectedEvents: []string{
+				"Warning Failed ConfigMap ns/failed-0: forbidden",
+				"Warning Failed ConfigMap ns/failed-1: forbidden",
+				"Warning Failed ConfigMap ns/failed-2: forbidden",
+				"Warning Failed ConfigMap ns/failed-3: forbidden",
+				"Warning Failed ConfigMap ns/failed-4: forbidden",
+				"Warning Failed 2 more objects could not be synced or deleted, see .status.targets",
+			},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(20)
+			nsRecorder := record.NewFakeRecorder(20)
+			r := &SyncConfigReconciler{Recorder: recorder, NamespaceRecorder: nsRecorder}
+			rc := &ReconciliationContext{cfg: &syncv1beta1.SyncConfig{ObjectMeta: toObjectMeta("config", "espejo")}}
+			obj := toUnstructured(t, &corev1.ConfigMap{TypeMeta: metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"}, ObjectMeta: toObjectMeta("synced", "ns")})
+			for _, op := range tt.changes {
+				r.recordChange(rc, &obj, op)
+			}
+			for i := 0; i < tt.failures; i++ {
+				failed := toUnstructured(t, &corev1.ConfigMap{TypeMeta: metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"}, ObjectMeta: toObjectMeta(fmt.Sprintf("failed-%d", i), "ns")})
+				rc.IncrementFailCount(errors.New("forbidden"))
+				rc.AddTarget(&failed, syncv1beta1.TargetResultFailed, "forbidden")
+			}
+
+			r.recordEvents(rc)
+
+			assert.Equal(t, tt.expectedEvents, drainEvents(recorder))
+			assert.Len(t, drainEvents(nsRecorder), tt.expectedNamespaceEvents)
+		})
+	}
+}
+
+func Test_RateLimitedRecorder_GivenBurstExceeded_ThenDropEvents(t *testing.T) {
+	fake := record.NewFakeRecorder(10)
+	recorder := NewRateLimitedRecorder(fake, 0.001, 2)
+	obj := &corev1.ConfigMap{ObjectMeta: toObjectMeta("cm", "ns")}
+
+	for i := 0; i < 5; i++ {
+		recorder.Event(obj, corev1.EventTypeNormal, EventReasonSynced, "message")
+	}
+
+	assert.Len(t, drainEvents(fake), 2)
+}
+
+func drainEvents(recorder *record.FakeRecorder) []string {
+	var events []string
+	for {
+		select {
+		case event := <-recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
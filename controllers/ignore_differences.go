@@ -0,0 +1,234 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/vshn/espejo/api/v1beta1"
+)
+
+type (
+	// fieldPath is a parsed path to a field of an object. Each element is either a map key or a list index.
+	fieldPath []string
+
+	// ignoreDifference is an IgnoreDifference with parsed field paths.
+	ignoreDifference struct {
+		v1beta1.IgnoreDifference
+		paths []fieldPath
+	}
+)
+
+// parseIgnoreDifferences parses the field paths of the given IgnoreDifferences.
+func parseIgnoreDifferences(entries []v1beta1.IgnoreDifference) ([]ignoreDifference, error) {
+	parsed := make([]ignoreDifference, 0, len(entries))
+	for _, entry := range entries {
+		diff := ignoreDifference{IgnoreDifference: entry}
+		for _, pointer := range entry.JSONPointers {
+			path, err := parseJSONPointer(pointer)
+			if err != nil {
+				return nil, err
+			}
+			diff.paths = append(diff.paths, path)
+		}
+		for _, jsonPath := range entry.JSONPaths {
+			path, err := parseJSONPath(jsonPath)
+			if err != nil {
+				return nil, err
+			}
+			diff.paths = append(diff.paths, path)
+		}
+		parsed = append(parsed, diff)
+	}
+	return parsed, nil
+}
+
+// matches returns true if the entry applies to the given object.
+func (d ignoreDifference) matches(obj *unstructured.Unstructured) bool {
+	return (d.APIVersion == "" || d.APIVersion == obj.GetAPIVersion()) &&
+		(d.Kind == "" || d.Kind == obj.GetKind()) &&
+		(d.Name == "" || d.Name == obj.GetName())
+}
+
+// preserveIgnoredFields copies the values of all ignored fields that apply to desired from live into desired.
+// Ignored fields that do not exist in live are removed from desired.
+func preserveIgnoredFields(diffs []ignoreDifference, desired, live *unstructured.Unstructured) {
+	for _, diff := range diffs {
+		if !diff.matches(desired) {
+			continue
+		}
+		for _, path := range diff.paths {
+			value, found := lookupField(live.Object, path)
+			if found {
+				setField(desired.Object, path, runtime.DeepCopyJSONValue(value))
+			} else {
+				removeField(desired.Object, path)
+			}
+		}
+	}
+}
+
+// removeIgnoredFields removes all ignored fields that apply to the given object from it.
+// With server-side apply, this leaves the ownership of the ignored fields to the other field managers.
+func removeIgnoredFields(diffs []ignoreDifference, obj *unstructured.Unstructured) {
+	for _, diff := range diffs {
+		if !diff.matches(obj) {
+			continue
+		}
+		for _, path := range diff.paths {
+			removeField(obj.Object, path)
+		}
+	}
+}
+
+// hasIgnoredFields returns true if any of the given entries applies to the given object.
+func hasIgnoredFields(diffs []ignoreDifference, obj *unstructured.Unstructured) bool {
+	for _, diff := range diffs {
+		if diff.matches(obj) && len(diff.paths) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// parseJSONPointer parses a JSON pointer as defined in RFC 6901, e.g. "/metadata/annotations/example.com~1key".
+func parseJSONPointer(pointer string) (fieldPath, error) {
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("JSON pointer %q must start with '/'", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	path := make(fieldPath, 0, len(tokens))
+	for _, token := range tokens {
+		token = strings.ReplaceAll(token, "~1", "/")
+		token = strings.ReplaceAll(token, "~0", "~")
+		path = append(path, token)
+	}
+	return path, nil
+}
+
+// parseJSONPath parses a JSONPath expression consisting of child and index selectors only,
+// e.g. "{.metadata.annotations['example.com/key']}" or "$.spec.containers[0].image".
+func parseJSONPath(expression string) (fieldPath, error) {
+	s := strings.TrimSpace(expression)
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "{"), "}")
+	s = strings.TrimPrefix(s, "$")
+	if s == "" {
+		return nil, fmt.Errorf("JSONPath %q is empty", expression)
+	}
+
+	var path fieldPath
+	for len(s) > 0 {
+		switch s[0] {
+		case '.':
+			end := strings.IndexAny(s[1:], ".[")
+			if end < 0 {
+				end = len(s) - 1
+			}
+			name := s[1 : end+1]
+			if name == "" {
+				return nil, fmt.Errorf("JSONPath %q contains an empty field name", expression)
+			}
+			path = append(path, name)
+			s = s[end+1:]
+		case '[':
+			end := strings.IndexByte(s, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("JSONPath %q contains an unterminated '['", expression)
+			}
+			selector := s[1:end]
+			if unquoted, ok := unquoteJSONPathKey(selector); ok {
+				path = append(path, unquoted)
+			} else if _, err := strconv.Atoi(selector); err == nil {
+				path = append(path, selector)
+			} else {
+				return nil, fmt.Errorf("JSONPath %q contains an unsupported selector %q", expression, selector)
+			}
+			s = s[end+1:]
+		default:
+			return nil, fmt.Errorf("JSONPath %q must start with '.' or '['", expression)
+		}
+	}
+	return path, nil
+}
+
+func unquoteJSONPathKey(selector string) (string, bool) {
+	if len(selector) >= 2 && (selector[0] == '\'' || selector[0] == '"') && selector[len(selector)-1] == selector[0] {
+		return selector[1 : len(selector)-1], true
+	}
+	return "", false
+}
+
+// lookupField returns the value at the given path.
+func lookupField(obj interface{}, path fieldPath) (interface{}, bool) {
+	current := obj
+	for _, element := range path {
+		switch typed := current.(type) {
+		case map[string]interface{}:
+			value, found := typed[element]
+			if !found {
+				return nil, false
+			}
+			current = value
+		case []interface{}:
+			index, err := strconv.Atoi(element)
+			if err != nil || index < 0 || index >= len(typed) {
+				return nil, false
+			}
+			current = typed[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// setField sets the value at the given path. Missing maps along the path are created.
+// The value is not set if the path traverses a missing list element or a value that is neither a map nor a list.
+func setField(obj map[string]interface{}, path fieldPath, value interface{}) {
+	var current interface{} = obj
+	for i, element := range path {
+		last := i == len(path)-1
+		switch typed := current.(type) {
+		case map[string]interface{}:
+			if last {
+				typed[element] = value
+				return
+			}
+			next, found := typed[element]
+			if !found || next == nil {
+				next = map[string]interface{}{}
+				typed[element] = next
+			}
+			current = next
+		case []interface{}:
+			index, err := strconv.Atoi(element)
+			if err != nil || index < 0 || index >= len(typed) {
+				return
+			}
+			if last {
+				typed[index] = value
+				return
+			}
+			current = typed[index]
+		default:
+			return
+		}
+	}
+}
+
+// removeField removes the map key at the given path. List elements are not removed.
+func removeField(obj map[string]interface{}, path fieldPath) {
+	if len(path) == 0 {
+		return
+	}
+	parent, found := lookupField(obj, path[:len(path)-1])
+	if !found {
+		return
+	}
+	if m, ok := parent.(map[string]interface{}); ok {
+		delete(m, path[len(path)-1])
+	}
+}
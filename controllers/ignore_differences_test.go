@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/vshn/espejo/api/v1beta1"
+)
+
+func Test_ParseJSONPointer(t *testing.T) {
+	tests := map[string]struct {
+		pointer   string
+		expected  fieldPath
+		expectErr bool
+	}{
+		"GivenSimplePointer_ThenSplitTokens": {
+			pointer:  "/spec/replicas",
+			expected: fieldPath{"spec", "replicas"},
+		},
+		"GivenEscapedPointer_ThenUnescapeTokens": {
+			pointer:  "/metadata/annotations/example.com~1key~0x",
+			expected: fieldPath{"metadata", "annotations", "example.com/key~x"},
+		},
+		"GivenRelativePointer_ThenReturnError": {
+			pointer:   "spec/replicas",
+			expectErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			path, err := parseJSONPointer(tt.pointer)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, path)
+		})
+	}
+}
+
+func Test_ParseJSONPath(t *testing.T) {
+	tests := map[string]struct {
+		expression string
+		expected   fieldPath
+		expectErr  bool
+	}{
+		"GivenChildSelectors_ThenSplitFields": {
+			expression: ".spec.replicas",
+			expected:   fieldPath{"spec", "replicas"},
+		},
+		"GivenBracesAndRoot_ThenStripThem": {
+			expression: "{$.spec.replicas}",
+			expected:   fieldPath{"spec", "replicas"},
+		},
+		"GivenQuotedKey_ThenKeepDots": {
+			expression: ".metadata.annotations['example.com/key']",
+			expected:   fieldPath{"metadata", "annotations", "example.com/key"},
+		},
+		"GivenIndex_ThenIncludeIndex": {
+			expression: `.spec.containers[0]["image"]`,
+			expected:   fieldPath{"spec", "containers", "0", "image"},
+		},
+		"GivenFilter_ThenReturnError": {
+			expression: ".spec.containers[?(@.name=='app')]",
+			expectErr:  true,
+		},
+		"GivenUnterminatedBracket_ThenReturnError": {
+			expression: ".spec[0",
+			expectErr:  true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			path, err := parseJSONPath(tt.expression)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, path)
+		})
+	}
+}
+
+func Test_PreserveIgnoredFields(t *testing.T) {
+	desired := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "app"},
+		"spec": map[string]interface{}{
+			"replicas":   int64(1),
+			"containers": []interface{}{map[string]interface{}{"image": "app:v1"}},
+		},
+	}}
+	live := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":        "app",
+			"annotations": map[string]interface{}{"example.com/injected": "true"},
+		},
+		"spec": map[string]interface{}{
+			"replicas":   int64(5),
+			"containers": []interface{}{map[string]interface{}{"image": "app:v2"}},
+		},
+	}}
+	diffs, err := parseIgnoreDifferences([]v1beta1.IgnoreDifference{
+		{Kind: "Deployment", JSONPointers: []string{"/spec/replicas", "/metadata/annotations/example.com~1injected"}},
+		{Kind: "Deployment", Name: "app", JSONPaths: []string{".spec.containers[0].image", ".spec.paused"}},
+		{Kind: "StatefulSet", JSONPointers: []string{"/metadata"}},
+	})
+	require.NoError(t, err)
+	require.True(t, hasIgnoredFields(diffs, desired))
+
+	preserveIgnoredFields(diffs, desired, live)
+
+	assert.Equal(t, live.Object, desired.Object)
+}
+
+func Test_RemoveIgnoredFields(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "app"},
+		"spec":       map[string]interface{}{"replicas": int64(1), "paused": false},
+	}}
+	diffs, err := parseIgnoreDifferences([]v1beta1.IgnoreDifference{
+		{Kind: "Deployment", JSONPointers: []string{"/spec/replicas", "/spec/missing/field"}},
+		{Kind: "StatefulSet", JSONPointers: []string{"/spec/paused"}},
+	})
+	require.NoError(t, err)
+
+	removeIgnoredFields(diffs, obj)
+
+	assert.Equal(t, map[string]interface{}{"paused": false}, obj.Object["spec"])
+}
+
+func Test_ReconciliationContext_IgnoredFields_GivenItemOptions_ThenAppendItemEntries(t *testing.T) {
+	rc := &ReconciliationContext{cfg: &v1beta1.SyncConfig{Spec: v1beta1.SyncConfigSpec{
+		NamespaceSelector: &v1beta1.NamespaceSelector{MatchNames: []string{".*"}},
+		DeleteItems:       []v1beta1.DeleteMeta{{APIVersion: "v1", Kind: "ConfigMap", Name: "old"}},
+		IgnoreDifferences: []v1beta1.IgnoreDifference{{JSONPointers: []string{"/spec/replicas"}}},
+	}}}
+	require.NoError(t, rc.validateSpec())
+
+	ignored := rc.ignoredFields(v1beta1.SyncItemOptions{IgnoreDifferences: []v1beta1.IgnoreDifference{{JSONPaths: []string{".spec.paused"}}}})
+
+	require.Len(t, ignored, 2)
+	assert.Equal(t, []fieldPath{{"spec", "replicas"}}, ignored[0].paths)
+	assert.Equal(t, []fieldPath{{"spec", "paused"}}, ignored[1].paths)
+	assert.Len(t, rc.ignoredFields(v1beta1.SyncItemOptions{}), 1)
+}
@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-jsonnet"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
+)
+
+const (
+	// jsonnetFilename is the name of the Jsonnet snippet of the template in the error messages.
+	jsonnetFilename = "template.jsonnet"
+	// jsonnetMaxStack limits the depth of the evaluation, so that a runaway recursion in a template fails quickly
+	// instead of exhausting the memory of the operator.
+	jsonnetMaxStack = 200
+)
+
+// hasTemplate returns true if the given spec contains a Jsonnet template.
+func hasTemplate(spec syncv1beta1.SyncConfigSpec) bool {
+	return spec.Template != nil && spec.Template.Jsonnet != ""
+}
+
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get
+
+// fetchLibraries returns the library ConfigMaps of the template of the given SyncConfig in the order they are listed.
+// ConfigMaps that do not exist are left out, rendering the template fails until they are created.
+// The ConfigMaps are read as unstructured objects, so that a cached client does not start to cache all ConfigMaps.
+func fetchLibraries(ctx context.Context, c client.Reader, cfg *syncv1beta1.SyncConfig) ([]corev1.ConfigMap, error) {
+	if !hasTemplate(cfg.Spec) {
+		return nil, nil
+	}
+	configMaps := make([]corev1.ConfigMap, 0, len(cfg.Spec.Template.Libraries))
+	for _, name := range cfg.Spec.Template.Libraries {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("ConfigMap"))
+		err := c.Get(ctx, client.ObjectKey{Namespace: cfg.Namespace, Name: name}, obj)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch library ConfigMap %s: %w", name, err)
+		}
+		configMap := corev1.ConfigMap{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &configMap); err != nil {
+			return nil, err
+		}
+		configMaps = append(configMaps, configMap)
+	}
+	return configMaps, nil
+}
+
+// setLibraries makes the entries of the given ConfigMaps that are listed as libraries of the template importable.
+// Each entry can be imported as "<ConfigMap name>/<key>" and by its key. If several libraries contain the same key,
+// the key imports the entry of the library listed first.
+// Libraries missing in the given ConfigMaps are recorded, so that rendering the template fails.
+func (rc *ReconciliationContext) setLibraries(configMaps []corev1.ConfigMap) {
+	rc.libraries = map[string]jsonnet.Contents{}
+	rc.libraryVersions = libraryVersions(configMaps)
+	rc.missingLibraries = nil
+	if !hasTemplate(rc.cfg.Spec) {
+		return
+	}
+	for _, name := range rc.cfg.Spec.Template.Libraries {
+		found := false
+		for _, configMap := range configMaps {
+			if configMap.Name != name {
+				continue
+			}
+			found = true
+			for key, value := range configMap.Data {
+				contents := jsonnet.MakeContents(value)
+				rc.libraries[name+"/"+key] = contents
+				if _, found := rc.libraries[key]; !found {
+					rc.libraries[key] = contents
+				}
+			}
+		}
+		if !found {
+			rc.missingLibraries = append(rc.missingLibraries, name)
+		}
+	}
+}
+
+// renderTemplate evaluates the Jsonnet template of the SyncConfig for the given target namespace and returns the
+// resulting objects with the target namespace set. It returns no objects if the SyncConfig has no template.
+func (rc *ReconciliationContext) renderTemplate(targetNamespace corev1.Namespace) ([]*unstructured.Unstructured, error) {
+	if !hasTemplate(rc.cfg.Spec) {
+		return nil, nil
+	}
+	if len(rc.missingLibraries) > 0 {
+		return nil, fmt.Errorf("%w: library ConfigMap %s not found in namespace %s",
+			errRenderFailed, strings.Join(rc.missingLibraries, ", "), rc.cfg.Namespace)
+	}
+	values := placeholderValues{namespace: targetNamespace, cfg: rc.cfg, clusterName: rc.clusterName}
+	vm := jsonnet.MakeVM()
+	vm.MaxStack = jsonnetMaxStack
+	vm.Importer(&jsonnet.MemoryImporter{Data: rc.libraries})
+	for name, value := range map[string]interface{}{
+		"namespace":  targetNamespace,
+		"syncConfig": rc.cfg.ObjectMeta,
+		"parameters": values.parameters(),
+	} {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		vm.ExtCode(name, string(raw))
+	}
+
+	out, err := vm.EvaluateAnonymousSnippet(jsonnetFilename, rc.cfg.Spec.Template.Jsonnet)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errRenderFailed, err)
+	}
+	// Integers are decoded as int64 like in objects read from the API server, so that unchanged objects compare equal.
+	var result interface{}
+	if err := utiljson.Unmarshal([]byte(out), &result); err != nil {
+		return nil, fmt.Errorf("%w: %w", errRenderFailed, err)
+	}
+	list, ok := result.([]interface{})
+	if !ok {
+		list = []interface{}{result}
+	}
+	objs := make([]*unstructured.Unstructured, 0, len(list))
+	for i, entry := range list {
+		m, ok := entry.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: template result [%d] is not an object", errRenderFailed, i)
+		}
+		obj := &unstructured.Unstructured{Object: m}
+		if obj.GetAPIVersion() == "" || obj.GetKind() == "" || obj.GetName() == "" {
+			return nil, fmt.Errorf("%w: template result [%d] requires apiVersion, kind and metadata.name", errRenderFailed, i)
+		}
+		obj.SetNamespace(targetNamespace.Name)
+		objs = append(objs, obj)
+	}
+	return objs, nil
+}
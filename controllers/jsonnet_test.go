@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
+)
+
+func Test_ReconciliationContext_RenderTemplate(t *testing.T) {
+	ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "project", Labels: map[string]string{"tier": "gold"}}}
+	libraries := []corev1.ConfigMap{
+		{ObjectMeta: metav1.ObjectMeta{Name: "lib"}, Data: map[string]string{"quota.libsonnet": `{ cpu(tier):: if tier == "gold" then "8" else "2" }`}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "other"}, Data: map[string]string{"quota.libsonnet": `{ cpu(tier):: "1" }`}},
+	}
+
+	tests := map[string]struct {
+		givenJsonnet string
+		expected     []map[string]interface{}
+		expectedErr  string
+	}{
+		"GivenArray_ThenReturnObjectsInTargetNamespace": {
+			givenJsonnet: `
+local ns = std.extVar("namespace");
+[{
+  apiVersion: "v1",
+  kind: "ConfigMap",
+  metadata: { name: std.extVar("syncConfig").name + "-" + ns.metadata.labels.tier },
+  data: { project: std.extVar("parameters").PROJECT_NAME },
+}]`,
+			expected: []map[string]interface{}{{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata":   map[string]interface{}{"name": "config-gold", "namespace": "project"},
+				"data":       map[string]interface{}{"project": "project"},
+			}},
+		},
+		"GivenSingleObject_ThenReturnObject": {
+			givenJsonnet: `{ apiVersion: "v1", kind: "ServiceAccount", metadata: { name: "bot" } }`,
+			expected: []map[string]interface{}{{
+				"apiVersion": "v1",
+				"kind":       "ServiceAccount",
+				"metadata":   map[string]interface{}{"name": "bot", "namespace": "project"},
+			}},
+		},
+		"GivenImportByKey_ThenImportFromFirstLibrary": {
+			givenJsonnet: `
+local quota = import "quota.libsonnet";
+local other = import "other/quota.libsonnet";
+[{
+  apiVersion: "v1",
+  kind: "ResourceQuota",
+  metadata: { name: "quota" },
+  spec: { hard: { cpu: quota.cpu("gold"), memory: other.cpu("gold") } },
+}]`,
+			expected: []map[string]interface{}{{
+				"apiVersion": "v1",
+				"kind":       "ResourceQuota",
+				"metadata":   map[string]interface{}{"name": "quota", "namespace": "project"},
+				"spec":       map[string]interface{}{"hard": map[string]interface{}{"cpu": "8", "memory": "1"}},
+			}},
+		},
+		"GivenIntegerField_ThenDecodeAsInt64": {
+			givenJsonnet: `{ apiVersion: "coordination.k8s.io/v1", kind: "Lease", metadata: { name: "lease" }, spec: { leaseDurationSeconds: 30 } }`,
+			expected: []map[string]interface{}{{
+				"apiVersion": "coordination.k8s.io/v1",
+				"kind":       "Lease",
+				"metadata":   map[string]interface{}{"name": "lease", "namespace": "project"},
+				"spec":       map[string]interface{}{"leaseDurationSeconds": int64(30)},
+			}},
+		},
+		"GivenMissingImport_ThenReturnError": {
+			givenJsonnet: `import "missing.libsonnet"`,
+			expectedErr:  "could not render item",
+		},
+		"GivenRuntimeError_ThenReturnError": {
+			givenJsonnet: `error "no quota for tier " + std.extVar("namespace").metadata.labels.tier`,
+			expectedErr:  "no quota for tier gold",
+		},
+		"GivenRunawayRecursion_ThenReturnError": {
+			givenJsonnet: `local f(x) = f(x + 1) + 1; f(0)`,
+			expectedErr:  "max stack frames exceeded",
+		},
+		"GivenObjectWithoutName_ThenReturnError": {
+			givenJsonnet: `[{ apiVersion: "v1", kind: "ConfigMap" }]`,
+			expectedErr:  "template result [0] requires apiVersion, kind and metadata.name",
+		},
+		"GivenNonObject_ThenReturnError": {
+			givenJsonnet: `["value"]`,
+			expectedErr:  "template result [0] is not an object",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			rc := &ReconciliationContext{cfg: &syncv1beta1.SyncConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "espejo"},
+				Spec: syncv1beta1.SyncConfigSpec{Template: &syncv1beta1.Template{
+					Jsonnet:   tt.givenJsonnet,
+					Libraries: []string{"lib", "other"},
+				}},
+			}}
+			rc.setLibraries(libraries)
+
+			objs, err := rc.renderTemplate(ns)
+
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				assert.ErrorIs(t, err, errRenderFailed)
+				return
+			}
+			require.NoError(t, err)
+			result := make([]map[string]interface{}, 0, len(objs))
+			for _, obj := range objs {
+				result = append(result, obj.Object)
+			}
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func Test_ReconciliationContext_RenderTemplate_GivenNoTemplate_ThenReturnNothing(t *testing.T) {
+	rc := &ReconciliationContext{cfg: &syncv1beta1.SyncConfig{}}
+
+	objs, err := rc.renderTemplate(namespaceFromString("project"))
+
+	assert.NoError(t, err)
+	assert.Empty(t, objs)
+}
+
+func Test_ReconciliationContext_RenderTemplate_GivenMissingLibrary_ThenReturnError(t *testing.T) {
+	rc := &ReconciliationContext{cfg: &syncv1beta1.SyncConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "espejo"},
+		Spec: syncv1beta1.SyncConfigSpec{Template: &syncv1beta1.Template{
+			Jsonnet:   `[]`,
+			Libraries: []string{"lib", "missing"},
+		}},
+	}}
+	rc.setLibraries([]corev1.ConfigMap{{ObjectMeta: metav1.ObjectMeta{Name: "lib"}}})
+
+	_, err := rc.renderTemplate(namespaceFromString("project"))
+
+	assert.ErrorIs(t, err, errRenderFailed)
+	assert.ErrorContains(t, err, "library ConfigMap missing not found in namespace espejo")
+}
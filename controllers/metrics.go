@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
+)
+
+const (
+	// triggerSyncConfig labels reconciliations of all namespaces triggered by a SyncConfig.
+	triggerSyncConfig = "syncconfig"
+	// triggerNamespace labels reconciliations of a single namespace triggered by a namespace or a synced object.
+	triggerNamespace = "namespace"
+
+	// metricResultFailed and metricResultSkipped label objects that could not be synced or have been skipped.
+	metricResultFailed  = "failed"
+	metricResultSkipped = "skipped"
+	// metricResultDeleted labels objects that have been deleted.
+	metricResultDeleted = "deleted"
+)
+
+var (
+	itemsSyncedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "espejo_items_synced_total",
+		Help: "Number of sync items handled per result (created, updated, unchanged, recreated, failed, skipped).",
+	}, []string{"syncconfig", "namespace", "kind", "result"})
+	itemsDeletedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "espejo_items_deleted_total",
+		Help: "Number of deleted, pruned or cleaned up objects per result (deleted, failed).",
+	}, []string{"syncconfig", "namespace", "kind", "result"})
+	forceRecreationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "espejo_force_recreations_total",
+		Help: "Number of objects that have been deleted and recreated because they could not be updated.",
+	}, []string{"syncconfig", "namespace", "kind"})
+	matchedNamespaces = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "espejo_matched_namespaces",
+		Help: "Number of namespaces targeted by a SyncConfig.",
+	}, []string{"syncconfig"})
+	reconcileDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "espejo_reconcile_duration_seconds",
+		Help:    "Duration of SyncConfig reconciliations per trigger (syncconfig, namespace).",
+		Buckets: prometheus.ExponentialBuckets(0.01, 2, 12),
+	}, []string{"syncconfig", "trigger"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(itemsSyncedTotal, itemsDeletedTotal, forceRecreationsTotal, matchedNamespaces, reconcileDuration)
+}
+
+// metricsKey returns the value of the syncconfig label of the given SyncConfig.
+func metricsKey(name types.NamespacedName) string {
+	return name.Namespace + "/" + name.Name
+}
+
+// namespaceLabel returns the value of the namespace label for the given object.
+// The label is empty if namespace labels are disabled to limit the cardinality of the metrics.
+func (r *SyncConfigReconciler) namespaceLabel(obj *unstructured.Unstructured) string {
+	if r.MetricsWithoutNamespace {
+		return ""
+	}
+	return obj.GetNamespace()
+}
+
+// observeSyncedItem counts the given sync item with the given result. Dry runs are not counted.
+func (r *SyncConfigReconciler) observeSyncedItem(rc *ReconciliationContext, obj *unstructured.Unstructured, result string) {
+	if rc.dryRun {
+		return
+	}
+	key := metricsKey(types.NamespacedName{Namespace: rc.cfg.Namespace, Name: rc.cfg.Name})
+	itemsSyncedTotal.WithLabelValues(key, r.namespaceLabel(obj), obj.GetKind(), result).Inc()
+	if result == string(operationResultRecreated) {
+		forceRecreationsTotal.WithLabelValues(key, r.namespaceLabel(obj), obj.GetKind()).Inc()
+	}
+}
+
+// observeDeletedItem counts the given deleted object with the given result. Dry runs are not counted.
+func (r *SyncConfigReconciler) observeDeletedItem(rc *ReconciliationContext, obj *unstructured.Unstructured, result string) {
+	if rc.dryRun {
+		return
+	}
+	key := metricsKey(types.NamespacedName{Namespace: rc.cfg.Namespace, Name: rc.cfg.Name})
+	itemsDeletedTotal.WithLabelValues(key, r.namespaceLabel(obj), obj.GetKind(), result).Inc()
+}
+
+// observeMatchedNamespaces records the number of namespaces targeted by the SyncConfig.
+// Reconciliations limited to a single namespace do not know the total number and are ignored.
+func (r *SyncConfigReconciler) observeMatchedNamespaces(cfg *syncv1beta1.SyncConfig, count int64) {
+	if r.NamespaceScope != "" {
+		return
+	}
+	matchedNamespaces.WithLabelValues(metricsKey(types.NamespacedName{Namespace: cfg.Namespace, Name: cfg.Name})).Set(float64(count))
+}
+
+// reconcileTrigger returns the value of the trigger label of the reconciliation duration.
+func (r *SyncConfigReconciler) reconcileTrigger() string {
+	if r.NamespaceScope != "" {
+		return triggerNamespace
+	}
+	return triggerSyncConfig
+}
+
+// deleteMetrics removes all metrics of the given SyncConfig, so that deleted SyncConfigs do not leave stale series.
+func deleteMetrics(name types.NamespacedName) {
+	labels := prometheus.Labels{"syncconfig": metricsKey(name)}
+	itemsSyncedTotal.DeletePartialMatch(labels)
+	itemsDeletedTotal.DeletePartialMatch(labels)
+	forceRecreationsTotal.DeletePartialMatch(labels)
+	matchedNamespaces.DeletePartialMatch(labels)
+	reconcileDuration.DeletePartialMatch(labels)
+}
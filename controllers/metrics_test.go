@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
+)
+
+func Test_SyncConfigReconciler_ObserveSyncedItem(t *testing.T) {
+	tests := map[string]struct {
+		withoutNamespace  bool
+		expectedNamespace string
+	}{
+		"GivenNamespaceLabel_ThenLabelWithNamespace": {
+			expectedNamespace: "target",
+		},
+		"GivenMetricsWithoutNamespace_ThenLabelWithEmptyNamespace": {
+			withoutNamespace:  true,
+			expectedNamespace: "",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := &syncv1beta1.SyncConfig{ObjectMeta: toObjectMeta("metrics", "espejo")}
+			key := types.NamespacedName{Namespace: cfg.Namespace, Name: cfg.Name}
+			defer deleteMetrics(key)
+			r := &SyncConfigReconciler{MetricsWithoutNamespace: tt.withoutNamespace}
+			rc := &ReconciliationContext{cfg: cfg}
+			obj := toUnstructured(t, &corev1.ConfigMap{TypeMeta: metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"}, ObjectMeta: toObjectMeta("cm", "target")})
+
+			r.observeSyncedItem(rc, &obj, string(operationResultRecreated))
+
+			assert.Equal(t, float64(1), testutil.ToFloat64(itemsSyncedTotal.WithLabelValues("espejo/metrics", tt.expectedNamespace, "ConfigMap", "recreated")))
+			assert.Equal(t, float64(1), testutil.ToFloat64(forceRecreationsTotal.WithLabelValues("espejo/metrics", tt.expectedNamespace, "ConfigMap")))
+		})
+	}
+}
+
+func Test_DeleteMetrics(t *testing.T) {
+	matchedNamespaces.WithLabelValues("espejo/deleted").Set(3)
+	matchedNamespaces.WithLabelValues("espejo/other").Set(1)
+	defer deleteMetrics(types.NamespacedName{Namespace: "espejo", Name: "other"})
+
+	deleteMetrics(types.NamespacedName{Namespace: "espejo", Name: "deleted"})
+
+	assert.Equal(t, 1, testutil.CollectAndCount(matchedNamespaces))
+}
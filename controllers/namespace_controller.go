@@ -2,16 +2,19 @@ package controllers
 
 import (
 	"context"
+	"strings"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
-	syncv1alpha1 "github.com/vshn/espejo/api/v1alpha1"
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
 )
 
 type (
@@ -22,6 +25,10 @@ type (
 		Scheme                  *runtime.Scheme
 		WatchNamespace          string
 		NewSyncConfigReconciler func() *SyncConfigReconciler
+		// Watcher enqueues the SyncConfigs and namespaces of synced objects that have been modified or deleted, if set.
+		Watcher *ItemWatcher
+		// Recorder emits events on the SyncConfigs that could not be reconciled for a namespace, if set.
+		Recorder record.EventRecorder
 	}
 	// NamespaceReconciliationContext holds parameters relevant for a single reconcile
 	NamespaceReconciliationContext struct {
@@ -30,11 +37,21 @@ type (
 	}
 )
 
-// SetupWithManager configures this reconciler with the given manager
+// SetupWithManager configures this reconciler with the given manager.
+// Namespaces are reconciled when they are created or their labels change, as this may change the targeting SyncConfigs.
+// If a watcher is set, namespaces are also reconciled when synced objects in them are modified or deleted.
 func (r *NamespaceReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	c, err := ctrl.NewControllerManagedBy(mgr).
 		For(&corev1.Namespace{}).
-		Complete(r)
+		WithEventFilter(predicate.LabelChangedPredicate{}).
+		Build(r)
+	if err != nil {
+		return err
+	}
+	if r.Watcher != nil {
+		r.Watcher.Controller = c
+	}
+	return nil
 }
 
 // +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
@@ -47,7 +64,10 @@ func (r *NamespaceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		namespace: ns,
 		ctx:       ctx,
 	}
-	name := req.Name
+	syncConfig, name, scoped := parseScopedRequest(req)
+	if !scoped {
+		name = req.Name
+	}
 	err := r.Client.Get(ctx, types.NamespacedName{Name: name}, ns)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
@@ -62,7 +82,14 @@ func (r *NamespaceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, nil
 	}
 
-	configList := &syncv1alpha1.SyncConfigList{}
+	if scoped {
+		return r.reconcileSyncConfigForNamespace(rc, syncConfig)
+	}
+	if targets := r.NewSyncConfigReconciler().RenderedTargets; targets != nil {
+		// The labels of the namespace may have changed, which changes the objects the SyncConfigs render into it.
+		targets.invalidateNamespace(ns.Name)
+	}
+	configList := &syncv1beta1.SyncConfigList{}
 
 	r.Log.Info("Reconciling from Namespace event", "namespace", name)
 	var options []client.ListOption
@@ -78,11 +105,55 @@ func (r *NamespaceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	return r.reconcileSyncConfigsForNamespace(rc, configList)
 }
 
-func (r *NamespaceReconciler) reconcileSyncConfigsForNamespace(rc *NamespaceReconciliationContext, configList *syncv1alpha1.SyncConfigList) (ctrl.Result, error) {
+// reconcileSyncConfigForNamespace reconciles the given SyncConfig for the namespace of the given context only,
+// e.g. to correct the drift of an object synced by the SyncConfig.
+func (r *NamespaceReconciler) reconcileSyncConfigForNamespace(rc *NamespaceReconciliationContext, key types.NamespacedName) (ctrl.Result, error) {
+	if r.WatchNamespace != "" && key.Namespace != r.WatchNamespace {
+		return ctrl.Result{}, nil
+	}
+	cfg := &syncv1beta1.SyncConfig{}
+	if err := r.Client.Get(rc.ctx, key, cfg); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.Log.Info("SyncConfig does not exist, ignoring reconcile.", "namespace", rc.namespace.Name, "syncConfig", key.String())
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	r.Log.Info("Reconciling from synced object event", "namespace", rc.namespace.Name, "syncConfig", key.String())
+	return r.reconcileSyncConfigsForNamespace(rc, &syncv1beta1.SyncConfigList{Items: []syncv1beta1.SyncConfig{*cfg}})
+}
+
+// newScopedRequest returns a request that reconciles only the given SyncConfig for the given namespace.
+// The namespace is appended to the name of the SyncConfig, as names of objects cannot contain slashes.
+func newScopedRequest(syncConfig types.NamespacedName, namespace string) ctrl.Request {
+	return ctrl.Request{NamespacedName: types.NamespacedName{Namespace: syncConfig.Namespace, Name: syncConfig.Name + "/" + namespace}}
+}
+
+// parseScopedRequest returns the SyncConfig and the namespace of the given request created with newScopedRequest.
+// It returns false if the request is a request of a namespace.
+func parseScopedRequest(req ctrl.Request) (types.NamespacedName, string, bool) {
+	if req.Namespace == "" {
+		return types.NamespacedName{}, "", false
+	}
+	name, namespace, found := strings.Cut(req.Name, "/")
+	if !found {
+		return types.NamespacedName{}, "", false
+	}
+	return types.NamespacedName{Namespace: req.Namespace, Name: name}, namespace, true
+}
+
+func (r *NamespaceReconciler) reconcileSyncConfigsForNamespace(rc *NamespaceReconciliationContext, configList *syncv1beta1.SyncConfigList) (ctrl.Result, error) {
 	scr := r.NewSyncConfigReconciler()
 	scr.NamespaceScope = rc.namespace.Name
 	for _, cfg := range configList.Items {
+		if !cfg.DeletionTimestamp.IsZero() {
+			continue
+		}
 		if result, err := scr.DoReconcile(rc.ctx, &cfg); err != nil {
+			if r.Recorder != nil {
+				r.Recorder.Eventf(&cfg, corev1.EventTypeWarning, EventReasonFailed, "Could not reconcile namespace %s: %v", rc.namespace.Name, err)
+			}
 			return result, err
 		}
 	}
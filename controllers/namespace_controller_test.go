@@ -13,8 +13,8 @@ import (
 	"k8s.io/apimachinery/pkg/util/rand"
 	ctrl "sigs.k8s.io/controller-runtime"
 
-	. "github.com/vshn/espejo/api/v1alpha1"
-	syncv1alpha1 "github.com/vshn/espejo/api/v1alpha1"
+	. "github.com/vshn/espejo/api/v1beta1"
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -56,6 +56,16 @@ func (ts *NamespaceControllerTestSuite) Test_GivenNamespaceReconciler_WhenAnIgno
 	ts.thenAssertResourceDoesNotExist(templateCm)
 }
 
+func (ts *NamespaceControllerTestSuite) Test_GivenNamespaceReconciler_WhenSyncedObjectDrifts_ThenSyncOwningSyncConfigOnly() {
+	templateCm, sc := ts.givenSyncConfig("*")
+
+	result, err := ts.reconciler.Reconcile(ts.Ctx, newScopedRequest(ts.MapToNamespacedName(sc), ts.scopedNs))
+
+	ts.Assert().NoError(err)
+	ts.Assert().Equal(time.Duration(0), result.RequeueAfter)
+	ts.thenAssertSyncHappenedOnlyInScopedNamespace(templateCm)
+}
+
 func (ts *NamespaceControllerTestSuite) whenReconciling() {
 	result, err := ts.reconciler.Reconcile(ts.Ctx, ts.mapToNamespaceRequest(ts.scopedNs))
 
@@ -91,7 +101,7 @@ func (ts *NamespaceControllerTestSuite) givenSyncConfig(matchNames string) (*cor
 	sc := &SyncConfig{
 		ObjectMeta: toObjectMeta("test-syncconfig", ts.NS),
 		Spec: SyncConfigSpec{
-			SyncItems:         []syncv1alpha1.Manifest{syncv1alpha1.Manifest{toUnstructured(ts.T(), cm)}},
+			SyncItems:         []syncv1beta1.SyncItem{{Manifest: syncv1beta1.Manifest{Unstructured: toUnstructured(ts.T(), cm)}}},
 			NamespaceSelector: &NamespaceSelector{MatchNames: []string{matchNames}},
 		},
 	}
@@ -122,3 +132,36 @@ func (ts *NamespaceControllerTestSuite) thenAssertResourceDoesNotExist(cm *corev
 	}
 	ts.Assert().False(ts.IsResourceExisting(ts.Ctx, nonExistingCm))
 }
+
+func (ts *NamespaceControllerTestSuite) Test_GivenCleanupUnmatchedNamespaces_WhenNamespaceLabelRemoved_ThenDeleteSyncedObjects() {
+	cm := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "labelled-configmap-" + rand.String(5)},
+	}
+	sc := &SyncConfig{
+		ObjectMeta: toObjectMeta("test-syncconfig", ts.NS),
+		Spec: SyncConfigSpec{
+			SyncItems: []syncv1beta1.SyncItem{{Manifest: syncv1beta1.Manifest{Unstructured: toUnstructured(ts.T(), cm)}}},
+			NamespaceSelector: &NamespaceSelector{LabelSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"espejo-test": ts.scopedNs},
+			}},
+			CleanupUnmatchedNamespaces: true,
+		},
+	}
+	ts.EnsureResources(sc)
+	ns := &corev1.Namespace{}
+	ts.FetchResource(types.NamespacedName{Name: ts.scopedNs}, ns)
+	ns.Labels = map[string]string{"espejo-test": ts.scopedNs}
+	ts.UpdateResources(ns)
+
+	ts.whenReconciling()
+	cm.Namespace = ts.scopedNs
+	ts.Assert().True(ts.IsResourceExisting(ts.Ctx, cm))
+
+	ts.FetchResource(types.NamespacedName{Name: ts.scopedNs}, ns)
+	ns.Labels = nil
+	ts.UpdateResources(ns)
+
+	ts.whenReconciling()
+	ts.Assert().False(ts.IsResourceExisting(ts.Ctx, cm))
+}
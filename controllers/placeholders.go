@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
+)
+
+// placeholderPattern matches the supported placeholders, optionally followed by a default value, e.g.
+// ${PROJECT_NAME}, ${NAMESPACE_LABEL:cost-center} or ${NAMESPACE_LABEL:cost-center:-unknown}.
+// Other strings in the form of ${...} are left untouched.
+var placeholderPattern = regexp.MustCompile(
+	`\$\{(PROJECT_NAME|NAMESPACE_UID|SYNCCONFIG_NAME|SYNCCONFIG_NAMESPACE|CLUSTER_NAME|NAMESPACE_LABEL:[^}:]+|NAMESPACE_ANNOTATION:[^}:]+)(:-[^}]*)?}`)
+
+// errRenderFailed is returned if a sync item could not be rendered for a target namespace.
+var errRenderFailed = errors.New("could not render item")
+
+// placeholderValues provides the values of the placeholders in the sync items rendered for a target namespace.
+type placeholderValues struct {
+	namespace   corev1.Namespace
+	cfg         *syncv1beta1.SyncConfig
+	clusterName string
+}
+
+// lookup returns the value of the given placeholder and whether it has a value.
+func (p placeholderValues) lookup(placeholder string) (string, bool) {
+	name, key, _ := strings.Cut(placeholder, ":")
+	switch name {
+	case "PROJECT_NAME":
+		return p.namespace.Name, true
+	case "NAMESPACE_UID":
+		return string(p.namespace.UID), p.namespace.UID != ""
+	case "NAMESPACE_LABEL":
+		value, found := p.namespace.Labels[key]
+		return value, found
+	case "NAMESPACE_ANNOTATION":
+		value, found := p.namespace.Annotations[key]
+		return value, found
+	case "SYNCCONFIG_NAME":
+		return p.cfg.Name, p.cfg.Name != ""
+	case "SYNCCONFIG_NAMESPACE":
+		return p.cfg.Namespace, p.cfg.Namespace != ""
+	case "CLUSTER_NAME":
+		return p.clusterName, p.clusterName != ""
+	}
+	return "", false
+}
+
+// replace replaces all placeholders in the given string.
+// Placeholders without a value are replaced with their default value, if they have one, or returned as error.
+func (p placeholderValues) replace(s string) (string, error) {
+	var missing []string
+	replaced := placeholderPattern.ReplaceAllStringFunc(s, func(match string) string {
+		groups := placeholderPattern.FindStringSubmatch(match)
+		if value, found := p.lookup(groups[1]); found {
+			return value
+		}
+		if defaultValue, hasDefault := strings.CutPrefix(groups[2], ":-"); hasDefault {
+			return defaultValue
+		}
+		missing = append(missing, match)
+		return match
+	})
+	if len(missing) > 0 {
+		return s, fmt.Errorf("no value for %s in namespace %s and no default value", strings.Join(missing, ", "), p.namespace.Name)
+	}
+	return replaced, nil
+}
+
+// parameters returns the values of all placeholders without arguments that have a value, by name.
+func (p placeholderValues) parameters() map[string]string {
+	parameters := map[string]string{}
+	for _, name := range []string{"PROJECT_NAME", "NAMESPACE_UID", "SYNCCONFIG_NAME", "SYNCCONFIG_NAMESPACE", "CLUSTER_NAME"} {
+		if value, found := p.lookup(name); found {
+			parameters[name] = value
+		}
+	}
+	return parameters
+}
@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
+)
+
+func Test_PlaceholderValues_Replace(t *testing.T) {
+	values := placeholderValues{
+		namespace: corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:        "project",
+			UID:         "ns-uid",
+			Labels:      map[string]string{"cost-center": "1234", "example.com/team": "blue", "empty": ""},
+			Annotations: map[string]string{"example.com/owner": "alice"},
+		}},
+		cfg:         &syncv1beta1.SyncConfig{ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "espejo"}},
+		clusterName: "production",
+	}
+
+	tests := map[string]struct {
+		given       string
+		expected    string
+		expectedErr string
+	}{
+		"GivenProjectName_ThenReplaceWithNamespaceName": {
+			given:    "${PROJECT_NAME}-suffix",
+			expected: "project-suffix",
+		},
+		"GivenNamespaceMetadata_ThenReplace": {
+			given:    "${NAMESPACE_UID} ${NAMESPACE_LABEL:cost-center} ${NAMESPACE_LABEL:example.com/team} ${NAMESPACE_ANNOTATION:example.com/owner}",
+			expected: "ns-uid 1234 blue alice",
+		},
+		"GivenSyncConfigAndCluster_ThenReplace": {
+			given:    "${SYNCCONFIG_NAMESPACE}/${SYNCCONFIG_NAME}@${CLUSTER_NAME}",
+			expected: "espejo/config@production",
+		},
+		"GivenEmptyLabel_ThenReplaceWithEmptyValue": {
+			given:    "${NAMESPACE_LABEL:empty:-default}",
+			expected: "",
+		},
+		"GivenMissingLabelWithDefault_ThenReplaceWithDefault": {
+			given:    "${NAMESPACE_LABEL:missing:-none} ${NAMESPACE_ANNOTATION:missing:-}",
+			expected: "none ",
+		},
+		"GivenMissingLabelWithoutDefault_ThenReturnError": {
+			given:       "${NAMESPACE_LABEL:missing} ${NAMESPACE_ANNOTATION:other}",
+			expectedErr: "no value for ${NAMESPACE_LABEL:missing}, ${NAMESPACE_ANNOTATION:other} in namespace project and no default value",
+		},
+		"GivenUnknownPlaceholder_ThenLeaveUntouched": {
+			given:    "${HOME} ${NAMESPACE_LABEL:} $PROJECT_NAME",
+			expected: "${HOME} ${NAMESPACE_LABEL:} $PROJECT_NAME",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			result, err := values.replace(tt.given)
+
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func Test_PlaceholderValues_Replace_GivenNoClusterName_ThenReturnError(t *testing.T) {
+	values := placeholderValues{namespace: namespaceFromString("project"), cfg: &syncv1beta1.SyncConfig{}}
+
+	_, err := values.replace("${CLUSTER_NAME}")
+
+	assert.Error(t, err)
+}
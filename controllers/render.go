@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
+)
+
+// RenderedNamespace holds the sync items of a SyncConfig rendered for a targeted namespace.
+type RenderedNamespace struct {
+	Namespace string
+	Items     []*unstructured.Unstructured
+}
+
+// RenderSyncConfig validates the given SyncConfig and renders its sync items for each of the given namespaces that it
+// targets, the same way a reconciliation does. Namespaces that are not active are skipped.
+// The given cluster name is the value of the ${CLUSTER_NAME} placeholder. The given ConfigMaps provide the libraries
+// of the template, they are matched by name.
+// Items that cannot be rendered are left out and their errors are returned joined, along with the rendered items.
+// It does not require a connection to a cluster.
+func RenderSyncConfig(cfg *syncv1beta1.SyncConfig, namespaces []corev1.Namespace, clusterName string, configMaps []corev1.ConfigMap) ([]RenderedNamespace, error) {
+	rc := &ReconciliationContext{cfg: cfg, clusterName: clusterName}
+	if err := rc.validateSpec(); err != nil {
+		return nil, err
+	}
+	rc.setLibraries(configMaps)
+	rendered := make([]RenderedNamespace, 0)
+	var errs []error
+	for _, ns := range rc.filterNamespaces(namespaces) {
+		if ns.Status.Phase != corev1.NamespaceActive {
+			continue
+		}
+		items, err := rc.renderItems(ns)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("namespace %s: %w", ns.Name, err))
+		}
+		rendered = append(rendered, RenderedNamespace{Namespace: ns.Name, Items: items})
+	}
+	return rendered, errors.Join(errs...)
+}
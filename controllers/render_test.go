@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
+)
+
+func Test_RenderSyncConfig(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+		ObjectMeta: toObjectMeta("config", ""),
+		Data:       map[string]string{"PROJECT_NAME": "${PROJECT_NAME}"},
+	}
+	terminating := namespaceFromString("project-terminating")
+	terminating.Status.Phase = corev1.NamespaceTerminating
+	active := func(name string) corev1.Namespace {
+		ns := namespaceFromString(name)
+		ns.Status.Phase = corev1.NamespaceActive
+		return ns
+	}
+
+	tests := map[string]struct {
+		givenMatchNames    []string
+		expectedNamespaces []string
+		expectedErr        string
+	}{
+		"GivenMatchingNamespaces_WhenRender_ThenRenderItemsPerActiveNamespace": {
+			givenMatchNames:    []string{"project-.*"},
+			expectedNamespaces: []string{"project-a", "project-b"},
+		},
+		"GivenNoMatchingNamespace_WhenRender_ThenRenderNothing": {
+			givenMatchNames:    []string{"other"},
+			expectedNamespaces: []string{},
+		},
+		"GivenInvalidPattern_WhenRender_ThenReturnError": {
+			givenMatchNames: []string{"("},
+			expectedErr:     ".spec.namespaceSelector.matchNames pattern invalid",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := &syncv1beta1.SyncConfig{Spec: syncv1beta1.SyncConfigSpec{
+				NamespaceSelector: &syncv1beta1.NamespaceSelector{MatchNames: tt.givenMatchNames},
+				SyncItems:         []syncv1beta1.SyncItem{{Manifest: syncv1beta1.Manifest{Unstructured: toUnstructured(t, cm)}}},
+			}}
+
+			rendered, err := RenderSyncConfig(cfg, []corev1.Namespace{active("project-a"), active("project-b"), terminating, active("default")}, "", nil)
+
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			namespaces := make([]string, 0)
+			for _, ns := range rendered {
+				namespaces = append(namespaces, ns.Namespace)
+				require.Len(t, ns.Items, 1)
+				assert.Equal(t, ns.Namespace, ns.Items[0].GetNamespace())
+				assert.Equal(t, map[string]interface{}{"PROJECT_NAME": ns.Namespace}, ns.Items[0].Object["data"])
+			}
+			assert.Equal(t, tt.expectedNamespaces, namespaces)
+		})
+	}
+}
+
+func Test_RenderSyncConfig_GivenMissingLabel_ThenReturnErrorForAffectedNamespaceOnly(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+		ObjectMeta: toObjectMeta("config", ""),
+		Data:       map[string]string{"COST_CENTER": "${NAMESPACE_LABEL:cost-center}"},
+	}
+	labeled := namespaceFromString("project-a")
+	labeled.Labels = map[string]string{"cost-center": "1234"}
+	unlabeled := namespaceFromString("project-b")
+	for _, ns := range []*corev1.Namespace{&labeled, &unlabeled} {
+		ns.Status.Phase = corev1.NamespaceActive
+	}
+	cfg := &syncv1beta1.SyncConfig{Spec: syncv1beta1.SyncConfigSpec{
+		NamespaceSelector: &syncv1beta1.NamespaceSelector{MatchNames: []string{"project-.*"}},
+		SyncItems:         []syncv1beta1.SyncItem{{Manifest: syncv1beta1.Manifest{Unstructured: toUnstructured(t, cm)}}},
+	}}
+
+	rendered, err := RenderSyncConfig(cfg, []corev1.Namespace{labeled, unlabeled}, "", nil)
+
+	assert.ErrorContains(t, err, "namespace project-b: ConfigMap config: could not render item: no value for ${NAMESPACE_LABEL:cost-center}")
+	require.Len(t, rendered, 2)
+	require.Len(t, rendered[0].Items, 1)
+	assert.Equal(t, map[string]interface{}{"COST_CENTER": "1234"}, rendered[0].Items[0].Object["data"])
+	assert.Empty(t, rendered[1].Items)
+}
@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
+)
+
+// RenderedTargets caches the objects each SyncConfig rendered in its last reconciliation, so that overlapping
+// SyncConfigs can be detected without rendering all other SyncConfigs in every reconciliation.
+// Full reconciliations record the objects of all namespaces, namespace scoped reconciliations update a single namespace.
+// The recorded objects are outdated if the SyncConfig changed, if the library ConfigMaps of its template changed or
+// if the labels of a namespace changed, as reported by invalidateNamespace.
+// It is safe for concurrent use.
+type RenderedTargets struct {
+	mu      sync.RWMutex
+	configs map[types.UID]*renderedTargetsEntry
+}
+
+type renderedTargetsEntry struct {
+	// generation is the generation of the SyncConfig that rendered the objects.
+	generation int64
+	// libraries identifies the versions of the library ConfigMaps the objects were rendered with, see libraryVersions.
+	libraries string
+	// namespaces holds the target keys of the rendered objects per namespace.
+	namespaces map[string]map[string]bool
+	// invalidated holds the namespaces whose objects are outdated until they are recorded again.
+	invalidated map[string]bool
+}
+
+// NewRenderedTargets creates an empty RenderedTargets.
+func NewRenderedTargets() *RenderedTargets {
+	return &RenderedTargets{configs: map[types.UID]*renderedTargetsEntry{}}
+}
+
+// get returns the target keys the given SyncConfig rendered into the given namespace with the library ConfigMaps of
+// the given versions. It returns false if these objects are not known or outdated.
+func (t *RenderedTargets) get(cfg *syncv1beta1.SyncConfig, libraries, namespace string) (map[string]bool, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	entry, found := t.configs[cfg.UID]
+	if !found || entry.generation != cfg.Generation || entry.libraries != libraries || entry.invalidated[namespace] {
+		return nil, false
+	}
+	return entry.namespaces[namespace], true
+}
+
+// setAll replaces the recorded objects of the given SyncConfig with the given target keys per namespace, rendered with
+// the library ConfigMaps of the given versions.
+func (t *RenderedTargets) setAll(cfg *syncv1beta1.SyncConfig, libraries string, namespaces map[string]map[string]bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.configs[cfg.UID] = &renderedTargetsEntry{generation: cfg.Generation, libraries: libraries, namespaces: namespaces}
+}
+
+// set replaces the recorded objects of the given SyncConfig in the given namespace with the given target keys.
+// Nothing is recorded if the objects of the other namespaces are not known for the current generation of the SyncConfig.
+// If the objects have been rendered with other versions of the library ConfigMaps, all recorded objects of the
+// SyncConfig are dropped, as the objects of the other namespaces are outdated.
+func (t *RenderedTargets) set(cfg *syncv1beta1.SyncConfig, libraries, namespace string, keys map[string]bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	entry, found := t.configs[cfg.UID]
+	if !found || entry.generation != cfg.Generation {
+		return
+	}
+	if entry.libraries != libraries {
+		delete(t.configs, cfg.UID)
+		return
+	}
+	delete(entry.invalidated, namespace)
+	if len(keys) == 0 {
+		delete(entry.namespaces, namespace)
+		return
+	}
+	entry.namespaces[namespace] = keys
+}
+
+// invalidateNamespace marks the recorded objects of all SyncConfigs in the given namespace as outdated,
+// e.g. because the labels of the namespace changed.
+func (t *RenderedTargets) invalidateNamespace(namespace string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, entry := range t.configs {
+		if entry.invalidated == nil {
+			entry.invalidated = map[string]bool{}
+		}
+		entry.invalidated[namespace] = true
+	}
+}
+
+// retain removes the recorded objects of all SyncConfigs that are not in the given list.
+func (t *RenderedTargets) retain(configs []syncv1beta1.SyncConfig) {
+	uids := make(map[types.UID]bool, len(configs))
+	for _, cfg := range configs {
+		uids[cfg.UID] = true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for uid := range t.configs {
+		if !uids[uid] {
+			delete(t.configs, uid)
+		}
+	}
+}
+
+// libraryVersions identifies the given library ConfigMaps by their names and resource versions.
+func libraryVersions(configMaps []corev1.ConfigMap) string {
+	versions := make([]string, 0, len(configMaps))
+	for _, configMap := range configMaps {
+		versions = append(versions, configMap.Name+"@"+configMap.ResourceVersion)
+	}
+	return strings.Join(versions, ",")
+}
@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
+)
+
+func Test_RenderedTargets(t *testing.T) {
+	cfg := &syncv1beta1.SyncConfig{ObjectMeta: metav1.ObjectMeta{UID: "uid", Generation: 1}}
+	targets := NewRenderedTargets()
+
+	_, known := targets.get(cfg, "", "ns")
+	assert.False(t, known, "unknown config")
+
+	targets.set(cfg, "", "ns", map[string]bool{"ns//ConfigMap/scoped": true})
+	_, known = targets.get(cfg, "", "ns")
+	assert.False(t, known, "scoped update without full reconciliation")
+
+	targets.setAll(cfg, "", map[string]map[string]bool{"ns": {"ns//ConfigMap/first": true}})
+	keys, known := targets.get(cfg, "", "ns")
+	assert.True(t, known)
+	assert.Equal(t, map[string]bool{"ns//ConfigMap/first": true}, keys)
+	keys, known = targets.get(cfg, "", "other")
+	assert.True(t, known)
+	assert.Empty(t, keys)
+
+	targets.set(cfg, "", "other", map[string]bool{"other//ConfigMap/second": true})
+	keys, _ = targets.get(cfg, "", "other")
+	assert.Equal(t, map[string]bool{"other//ConfigMap/second": true}, keys)
+	targets.set(cfg, "", "ns", nil)
+	keys, _ = targets.get(cfg, "", "ns")
+	assert.Empty(t, keys)
+
+	changed := cfg.DeepCopy()
+	changed.Generation = 2
+	_, known = targets.get(changed, "", "other")
+	assert.False(t, known, "changed generation")
+
+	targets.retain([]syncv1beta1.SyncConfig{{ObjectMeta: metav1.ObjectMeta{UID: "other-uid"}}})
+	_, known = targets.get(cfg, "", "other")
+	assert.False(t, known, "removed config")
+}
+
+func Test_RenderedTargets_GivenInvalidatedNamespace_ThenUnknownUntilRecorded(t *testing.T) {
+	cfg := &syncv1beta1.SyncConfig{ObjectMeta: metav1.ObjectMeta{UID: "uid", Generation: 1}}
+	targets := NewRenderedTargets()
+	targets.setAll(cfg, "", map[string]map[string]bool{"ns": {"ns//ConfigMap/first": true}})
+
+	targets.invalidateNamespace("ns")
+
+	_, known := targets.get(cfg, "", "ns")
+	assert.False(t, known, "invalidated namespace")
+	_, known = targets.get(cfg, "", "other")
+	assert.True(t, known, "other namespace")
+	targets.set(cfg, "", "ns", nil)
+	keys, known := targets.get(cfg, "", "ns")
+	assert.True(t, known, "recorded again")
+	assert.Empty(t, keys)
+}
+
+func Test_RenderedTargets_GivenChangedLibraries_ThenUnknown(t *testing.T) {
+	cfg := &syncv1beta1.SyncConfig{ObjectMeta: metav1.ObjectMeta{UID: "uid", Generation: 1}}
+	targets := NewRenderedTargets()
+	libraries := libraryVersions([]corev1.ConfigMap{{ObjectMeta: metav1.ObjectMeta{Name: "lib", ResourceVersion: "1"}}})
+	changed := libraryVersions([]corev1.ConfigMap{{ObjectMeta: metav1.ObjectMeta{Name: "lib", ResourceVersion: "2"}}})
+	targets.setAll(cfg, libraries, map[string]map[string]bool{"ns": {"ns//ConfigMap/first": true}})
+
+	_, known := targets.get(cfg, changed, "ns")
+	assert.False(t, known, "changed library")
+
+	targets.set(cfg, changed, "ns", map[string]bool{"ns//ConfigMap/second": true})
+	_, known = targets.get(cfg, changed, "other")
+	assert.False(t, known, "scoped update with changed library drops the other namespaces")
+}
+
+func Test_SyncConfigReconciler_AddCachedOverlaps(t *testing.T) {
+	other := &syncv1beta1.SyncConfig{ObjectMeta: metav1.ObjectMeta{UID: "other", Generation: 1}}
+	otherRC := &ReconciliationContext{cfg: other, renderedItems: map[string]map[string]bool{}}
+	obj := toUnstructured(t, &corev1.ConfigMap{TypeMeta: metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"}, ObjectMeta: toObjectMeta("shared", "ns")})
+	otherRC.markRendered(&obj)
+	r := &SyncConfigReconciler{RenderedTargets: NewRenderedTargets()}
+	r.recordRenderedTargets(otherRC)
+	rc := &ReconciliationContext{cfg: &syncv1beta1.SyncConfig{}, overlaps: map[string][]*syncv1beta1.SyncConfig{}}
+
+	assert.True(t, r.addCachedOverlaps(rc, other, "", []corev1.Namespace{namespaceFromString("ns"), namespaceFromString("unrelated")}))
+
+	assert.Equal(t, map[string][]*syncv1beta1.SyncConfig{targetKey(&obj): {other}}, rc.overlaps)
+	assert.False(t, r.addCachedOverlaps(rc, &syncv1beta1.SyncConfig{ObjectMeta: metav1.ObjectMeta{UID: "unknown"}}, "", []corev1.Namespace{namespaceFromString("ns")}))
+}
@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
+)
+
+var (
+	// errItemSkipped is returned if a rendered sync item must not be synced into its namespace.
+	errItemSkipped = errors.New("item skipped")
+	// errItemConflict is returned if a rendered sync item conflicts with an existing object.
+	errItemConflict = errors.New("conflict with existing object")
+)
+
+// checkConflictPolicy applies the given conflict policy to the given rendered item.
+// It returns errItemSkipped if the item should be skipped and an error if the item should be counted as failed.
+func (r *SyncConfigReconciler) checkConflictPolicy(rc *ReconciliationContext, policy syncv1beta1.ConflictPolicy, obj *unstructured.Unstructured) error {
+	if policy == "" || policy == syncv1beta1.ConflictPolicyAdopt {
+		return nil
+	}
+
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(obj.GroupVersionKind())
+	err := r.Client.Get(rc.ctx, client.ObjectKeyFromObject(obj), existing)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if existing.GetLabels()[syncv1beta1.LabelManagedBy] == syncv1beta1.ManagedByEspejo {
+		return nil
+	}
+
+	if policy == syncv1beta1.ConflictPolicySkip {
+		return fmt.Errorf("%w: object already exists and has not been synced by espejo", errItemSkipped)
+	}
+	return fmt.Errorf("%w: object already exists and has not been synced by espejo, conflict policy is %q", errItemConflict, policy)
+}
+
+// detectOverlaps records the objects that are rendered into the given namespaces by this and other SyncConfigs.
+// The objects of the other SyncConfigs are taken from RenderedTargets if they have been recorded for their current
+// generation and library ConfigMaps, otherwise their sync items and templates are rendered.
+// Invalid and deleted SyncConfigs are ignored.
+func (r *SyncConfigReconciler) detectOverlaps(rc *ReconciliationContext, namespaces []corev1.Namespace) error {
+	rc.overlaps = map[string][]*syncv1beta1.SyncConfig{}
+	if len(namespaces) == 0 {
+		return nil
+	}
+
+	configList := &syncv1beta1.SyncConfigList{}
+	var options []client.ListOption
+	if r.WatchNamespace != "" {
+		options = append(options, client.InNamespace(r.WatchNamespace))
+	}
+	if err := r.Client.List(rc.ctx, configList, options...); err != nil {
+		return err
+	}
+	if r.RenderedTargets != nil {
+		r.RenderedTargets.retain(configList.Items)
+	}
+	for i := range configList.Items {
+		other := &configList.Items[i]
+		if other.UID == rc.cfg.UID || !other.DeletionTimestamp.IsZero() {
+			continue
+		}
+		libraries, err := fetchLibraries(rc.ctx, r.Client, other)
+		if err != nil {
+			return err
+		}
+		if r.addCachedOverlaps(rc, other, libraryVersions(libraries), namespaces) {
+			continue
+		}
+		otherRC := &ReconciliationContext{ctx: rc.ctx, cfg: other, clusterName: rc.clusterName}
+		if otherRC.validateSpec() != nil {
+			continue
+		}
+		otherRC.setLibraries(libraries)
+		for _, ns := range otherRC.filterNamespaces(namespaces) {
+			// Items that cannot be rendered are not synced, so they cannot overlap.
+			items, _ := otherRC.renderItems(ns)
+			for _, obj := range items {
+				key := targetKey(obj)
+				rc.overlaps[key] = append(rc.overlaps[key], other)
+			}
+		}
+	}
+	return nil
+}
+
+// addCachedOverlaps records the objects that the given other SyncConfig rendered into the given namespaces with the
+// library ConfigMaps of the given versions according to RenderedTargets. It returns false if they are not known.
+func (r *SyncConfigReconciler) addCachedOverlaps(rc *ReconciliationContext, other *syncv1beta1.SyncConfig, libraries string, namespaces []corev1.Namespace) bool {
+	if r.RenderedTargets == nil {
+		return false
+	}
+	for _, ns := range namespaces {
+		keys, known := r.RenderedTargets.get(other, libraries, ns.Name)
+		if !known {
+			return false
+		}
+		for key := range keys {
+			rc.overlaps[key] = append(rc.overlaps[key], other)
+		}
+	}
+	return true
+}
+
+// recordRenderedTargets records the objects rendered by this reconciliation in RenderedTargets.
+// Namespace scoped reconciliations only update the objects of their namespace.
+func (r *SyncConfigReconciler) recordRenderedTargets(rc *ReconciliationContext) {
+	if r.RenderedTargets == nil {
+		return
+	}
+	namespaces := make(map[string]map[string]bool, len(rc.renderedItems))
+	for namespace, items := range rc.renderedItems {
+		keys := make(map[string]bool, len(items))
+		for item := range items {
+			keys[namespace+"/"+item] = true
+		}
+		namespaces[namespace] = keys
+	}
+	if r.NamespaceScope != "" {
+		r.RenderedTargets.set(rc.cfg, rc.libraryVersions, r.NamespaceScope, namespaces[r.NamespaceScope])
+		return
+	}
+	r.RenderedTargets.setAll(rc.cfg, rc.libraryVersions, namespaces)
+}
+
+// checkOverlap records other SyncConfigs that render the given object.
+// It returns errItemSkipped if any of them takes precedence over this SyncConfig.
+func (rc *ReconciliationContext) checkOverlap(obj *unstructured.Unstructured) error {
+	others := rc.overlaps[targetKey(obj)]
+	var winner *syncv1beta1.SyncConfig
+	for _, other := range others {
+		rc.AddConflictingConfig(other)
+		if takesPrecedence(other, rc.cfg) && (winner == nil || takesPrecedence(other, winner)) {
+			winner = other
+		}
+	}
+	if winner == nil {
+		return nil
+	}
+	if winner.Spec.Priority == rc.cfg.Spec.Priority {
+		return fmt.Errorf("%w: object is synced by SyncConfig %s/%s with the same priority %d, which is first by namespace and name",
+			errItemSkipped, winner.Namespace, winner.Name, winner.Spec.Priority)
+	}
+	return fmt.Errorf("%w: object is synced by SyncConfig %s/%s with higher priority %d",
+		errItemSkipped, winner.Namespace, winner.Name, winner.Spec.Priority)
+}
+
+// takesPrecedence returns true if SyncConfig a syncs an object rendered by both given SyncConfigs.
+// The SyncConfig with the higher priority takes precedence, ties are broken by namespace and name.
+func takesPrecedence(a, b *syncv1beta1.SyncConfig) bool {
+	if a.Spec.Priority != b.Spec.Priority {
+		return a.Spec.Priority > b.Spec.Priority
+	}
+	if a.Namespace != b.Namespace {
+		return a.Namespace < b.Namespace
+	}
+	return a.Name < b.Name
+}
+
+// targetKey identifies an object across all namespaces regardless of the API version.
+func targetKey(obj *unstructured.Unstructured) string {
+	return obj.GetNamespace() + "/" + renderedItemKey(obj)
+}
@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
+)
+
+func Test_ReconciliationContext_CheckOverlap(t *testing.T) {
+	obj := toUnstructured(t, &corev1.ConfigMap{TypeMeta: metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"}, ObjectMeta: toObjectMeta("shared", "ns")})
+	tests := map[string]struct {
+		priority      int32
+		otherPriority int32
+		otherName     string
+		expectSkip    bool
+	}{
+		"GivenHigherPriority_WhenOverlapping_ThenSync": {
+			priority: 10, otherPriority: 5, otherName: "a-other", expectSkip: false,
+		},
+		"GivenSamePriority_WhenOtherIsLaterByName_ThenSync": {
+			priority: 5, otherPriority: 5, otherName: "other", expectSkip: false,
+		},
+		"GivenSamePriority_WhenOtherIsFirstByName_ThenSkip": {
+			priority: 5, otherPriority: 5, otherName: "a-other", expectSkip: true,
+		},
+		"GivenLowerPriority_WhenOverlapping_ThenSkip": {
+			priority: 0, otherPriority: 5, otherName: "other", expectSkip: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			other := &syncv1beta1.SyncConfig{
+				ObjectMeta: toObjectMeta(tt.otherName, "espejo"),
+				Spec:       syncv1beta1.SyncConfigSpec{Priority: tt.otherPriority},
+			}
+			rc := &ReconciliationContext{
+				cfg: &syncv1beta1.SyncConfig{
+					ObjectMeta: toObjectMeta("config", "espejo"),
+					Spec:       syncv1beta1.SyncConfigSpec{Priority: tt.priority},
+				},
+				overlaps: map[string][]*syncv1beta1.SyncConfig{targetKey(&obj): {other}},
+			}
+
+			err := rc.checkOverlap(&obj)
+
+			if tt.expectSkip {
+				assert.ErrorIs(t, err, errItemSkipped)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Len(t, rc.conflictingConfigs, 1)
+		})
+	}
+}
+
+func Test_ReconciliationContext_CheckOverlap_GivenNoOverlap_ThenSync(t *testing.T) {
+	obj := toUnstructured(t, &corev1.ConfigMap{TypeMeta: metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"}, ObjectMeta: toObjectMeta("own", "ns")})
+	rc := &ReconciliationContext{cfg: &syncv1beta1.SyncConfig{}}
+
+	assert.NoError(t, rc.checkOverlap(&obj))
+	assert.Empty(t, rc.conflictingConfigs)
+}
@@ -2,11 +2,15 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"regexp"
 	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/google/cel-go/cel"
+	"github.com/google/go-jsonnet"
+	"github.com/prometheus/client_golang/prometheus"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -14,12 +18,13 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
-	syncv1alpha1 "github.com/vshn/espejo/api/v1alpha1"
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
 )
 
 type (
@@ -33,70 +38,159 @@ type (
 		// NamespaceScope limits creations and deletions of sync items to this namespace, provided the selector still matches.
 		// If empty, the sync applies to all selector-matching namespaces.
 		NamespaceScope string
+		// Watcher starts watches for the kinds of the sync items, if set.
+		Watcher *ItemWatcher
+		// Recorder emits events on the SyncConfigs summarizing their changes and failures, if set.
+		Recorder record.EventRecorder
+		// NamespaceRecorder emits events about the synced objects in the target namespaces, if set.
+		NamespaceRecorder record.EventRecorder
+		// MetricsWithoutNamespace omits the namespace of the synced objects from the metrics to limit their cardinality.
+		MetricsWithoutNamespace bool
+		// DryRun reconciles all SyncConfigs in dry-run mode, regardless of their spec.
+		DryRun bool
+		// ClusterName is the value of the ${CLUSTER_NAME} placeholder.
+		ClusterName string
+		// RenderedTargets caches the objects rendered by all SyncConfigs to detect overlaps, if set.
+		// Without it, all other SyncConfigs are rendered in each reconciliation.
+		RenderedTargets *RenderedTargets
 	}
 	// ReconciliationContext holds the parameters of a single SyncConfig reconciliation
 	ReconciliationContext struct {
-		ctx              context.Context
-		cfg              *syncv1alpha1.SyncConfig
+		ctx context.Context
+		cfg *syncv1beta1.SyncConfig
+		// dryRun sends all requests that modify objects as server-side dry-run requests
+		dryRun bool
+		// clusterName is the value of the ${CLUSTER_NAME} placeholder
+		clusterName      string
 		matchNamesRegex  []*regexp.Regexp
 		ignoreNamesRegex []*regexp.Regexp
 		nsSelector       labels.Selector
-		syncCount        int64
-		deleteCount      int64
-		failCount        int64
+		// ignoreDifferences holds the parsed fields whose values are preserved in existing objects
+		ignoreDifferences []ignoreDifference
+		syncCount         int64
+		deleteCount       int64
+		failCount         int64
+		skipCount         int64
+		// renderedItems holds the keys of the rendered sync items per namespace
+		renderedItems map[string]map[string]bool
+		// renderedKinds holds the kinds of the rendered objects, including the ones rendered from the template
+		renderedKinds []syncv1beta1.ManagedKind
+		// renderFailedNamespaces holds the namespaces for which the template or a sync item could not be rendered
+		renderFailedNamespaces map[string]bool
+		// itemSelectors and deleteSelectors hold the parsed namespace selectors of the sync and delete items by index,
+		// nil for items without selector
+		itemSelectors   []*itemNamespaceSelector
+		deleteSelectors []*itemNamespaceSelector
+		// conditions holds the compiled when expressions of the sync items by index, nil for items without expression
+		conditions []cel.Program
+		// libraries holds the entries of the library ConfigMaps of the template by import path
+		libraries map[string]jsonnet.Contents
+		// libraryVersions identifies the versions of the library ConfigMaps of the template, see libraryVersions
+		libraryVersions string
+		// missingLibraries holds the names of the library ConfigMaps of the template that do not exist
+		missingLibraries []string
+		// managedKinds holds the kinds of objects that may have been synced by the SyncConfig
+		managedKinds []syncv1beta1.ManagedKind
+		// overlaps holds the other SyncConfigs rendering the same object per target key
+		overlaps map[string][]*syncv1beta1.SyncConfig
+		// conflictingConfigs holds the names of other SyncConfigs that sync the same objects as this SyncConfig
+		conflictingConfigs map[string]bool
+		// matchedNamespaceCount holds the number of namespaces targeted by the SyncConfig
+		matchedNamespaceCount int64
+		// targets holds the listed results of the handled objects per result
+		targets map[syncv1beta1.TargetResult][]syncv1beta1.TargetStatus
+		// targetCount holds the number of handled objects, including the ones not listed in targets
+		targetCount int64
+		// changes counts the changed objects for events
+		changes changeSummary
+		// failureReasons counts the failed objects per condition reason
+		failureReasons map[string]int64
 	}
 )
 
 // SetupWithManager configures this reconciler with the given manager
 func (r *SyncConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&syncv1alpha1.SyncConfig{}).
+		For(&syncv1beta1.SyncConfig{}).
 		WithEventFilter(predicate.GenerationChangedPredicate{}).
 		Complete(r)
 }
 
 // +kubebuilder:rbac:groups=sync.appuio.ch,resources=syncconfigs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=sync.appuio.ch,resources=syncconfigs/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=sync.appuio.ch,resources=syncconfigs/finalizers,verbs=update
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 // Reconcile retrieves a SyncConfig from the given reconcile request
 func (r *SyncConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	syncConfig := &syncv1alpha1.SyncConfig{}
+	syncConfig := &syncv1beta1.SyncConfig{}
 
 	err := r.Client.Get(ctx, req.NamespacedName, syncConfig)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
 			r.Log.Info("SyncConfig not found, ignoring reconcile.", "SyncConfig", req.NamespacedName)
+			deleteMetrics(req.NamespacedName)
 			return ctrl.Result{Requeue: false}, nil
 		}
 		r.Log.Error(err, "Could not retrieve SyncConfig.", "SyncConfig", req.NamespacedName)
 		return ctrl.Result{Requeue: true, RequeueAfter: r.ReconcileInterval}, err
 	}
 
+	if !syncConfig.DeletionTimestamp.IsZero() {
+		return r.reconcileDeletion(ctx, syncConfig)
+	}
+	if err := r.ensureFinalizer(ctx, syncConfig); err != nil {
+		r.Log.Error(err, "Could not update finalizers of SyncConfig.", getLoggingKeysAndValuesForSyncConfig(syncConfig)...)
+		return ctrl.Result{}, err
+	}
+
 	result, err := r.DoReconcile(ctx, syncConfig)
 	result.RequeueAfter = r.ReconcileInterval
 	return result, err
 }
 
 // DoReconcile is the actual reconciliation of the given SyncConfig
-func (r *SyncConfigReconciler) DoReconcile(ctx context.Context, syncConfig *syncv1alpha1.SyncConfig) (ctrl.Result, error) {
+func (r *SyncConfigReconciler) DoReconcile(ctx context.Context, syncConfig *syncv1beta1.SyncConfig) (ctrl.Result, error) {
+	timer := prometheus.NewTimer(reconcileDuration.WithLabelValues(
+		metricsKey(types.NamespacedName{Namespace: syncConfig.Namespace, Name: syncConfig.Name}), r.reconcileTrigger()))
+	defer timer.ObserveDuration()
+
 	rc := &ReconciliationContext{
-		ctx: ctx,
-		cfg: syncConfig,
+		ctx:                    ctx,
+		cfg:                    syncConfig,
+		renderedItems:          map[string]map[string]bool{},
+		renderFailedNamespaces: map[string]bool{},
+		managedKinds:           syncConfig.Status.ManagedKinds,
+		matchedNamespaceCount:  syncConfig.Status.MatchedNamespaceCount,
+		dryRun:                 r.isDryRun(syncConfig),
+		clusterName:            r.ClusterName,
 	}
 	r.Log.Info("Reconciling", getLoggingKeysAndValuesForSyncConfig(rc.cfg)...)
 	err := rc.validateSpec()
 	if err != nil {
-		rc.SetStatusCondition(CreateStatusConditionInvalid(err))
-		rc.SetStatusCondition(CreateStatusConditionReady(false))
+		rc.SetInvalidConditions(err)
 		return ctrl.Result{}, r.updateStatus(rc)
 	}
-	rc.SetStatusIfExisting(syncv1alpha1.ConditionInvalid, metav1.ConditionFalse)
+	rc.SetStatusIfExisting(syncv1beta1.ConditionInvalid, metav1.ConditionFalse)
+	r.watchSyncItems(rc)
 
 	namespaces, fetchErr := r.fetchNamespaces(rc)
 	if fetchErr != nil {
+		rc.SetStatusCondition(CreateStatusConditionProgressing(true, syncv1beta1.SyncReasonRetrying, fetchErr.Error()))
+		return ctrl.Result{RequeueAfter: 1 * time.Minute}, r.updateStatus(rc)
+	}
+	libraries, fetchErr := fetchLibraries(ctx, r.Client, rc.cfg)
+	if fetchErr != nil {
+		rc.SetStatusCondition(CreateStatusConditionProgressing(true, syncv1beta1.SyncReasonRetrying, fetchErr.Error()))
 		return ctrl.Result{RequeueAfter: 1 * time.Minute}, r.updateStatus(rc)
 	}
+	rc.setLibraries(libraries)
 	filteredNamespaces := rc.filterNamespaces(namespaces)
+	rc.matchedNamespaceCount = int64(len(filteredNamespaces))
+	r.observeMatchedNamespaces(rc.cfg, rc.matchedNamespaceCount)
+	if err := r.detectOverlaps(rc, filteredNamespaces); err != nil {
+		r.Log.Error(err, "Could not detect overlapping SyncConfigs", getLoggingKeysAndValuesForSyncConfig(rc.cfg)...)
+	}
 
 	for _, targetNamespace := range filteredNamespaces {
 		if targetNamespace.Status.Phase == corev1.NamespaceActive {
@@ -104,82 +198,249 @@ func (r *SyncConfigReconciler) DoReconcile(ctx context.Context, syncConfig *sync
 			r.syncItems(rc, targetNamespace)
 		}
 	}
+	r.watchKinds(rc.renderedKinds)
+	r.recordRenderedTargets(rc)
+	r.pruneItems(rc, namespaces, filteredNamespaces)
 	if rc.failCount > 0 {
 		r.Log.V(1).Info("Encountered errors", "err_count", rc.failCount)
 	}
-	if rc.isReconcileFailed() {
-		rc.SetStatusCondition(CreateStatusConditionReady(false))
-		rc.SetStatusCondition(CreateStatusConditionErrored(fmt.Errorf("could not sync or delete any items")))
-	} else {
-		rc.SetStatusCondition(CreateStatusConditionReady(true))
-	}
+	rc.SetConflictCondition()
+	r.recordEvents(rc)
+	rc.SetReconciledConditions()
 	return ctrl.Result{}, r.updateStatus(rc)
 }
 
 func (r *SyncConfigReconciler) syncItems(rc *ReconciliationContext, targetNamespace corev1.Namespace) {
-	for _, item := range rc.cfg.Spec.SyncItems {
-		obj := item.DeepCopy()
-		obj.Unstructured.SetNamespace(targetNamespace.Name)
-		replaceProjectName(targetNamespace.Name, obj.Unstructured.Object)
+	for i, item := range rc.cfg.Spec.SyncItems {
+		obj, err := rc.renderSyncItem(i, targetNamespace)
+		if obj == nil {
+			continue
+		}
 
-		err := r.syncItem(rc, &obj.Unstructured, rc.cfg.Spec.ForceRecreate)
-		if err != nil {
-			r.Log.Error(err, "Error syncing object", getLoggingKeysAndValues(&obj.Unstructured)...)
-			rc.IncrementFailCount()
-		} else {
-			rc.IncrementSyncCount()
+		var op controllerutil.OperationResult
+		if err == nil {
+			op, err = r.syncRenderedItem(rc, item.Options, obj)
 		}
+		r.handleSyncResult(rc, obj, op, err)
 	}
+	r.syncTemplate(rc, targetNamespace)
 	return
 }
-func (r *SyncConfigReconciler) syncItem(rc *ReconciliationContext, obj *unstructured.Unstructured, force bool) error {
+
+// syncTemplate syncs the objects rendered from the template of the SyncConfig into the given namespace with the
+// default options. If the template cannot be rendered, no objects are pruned from the namespace.
+func (r *SyncConfigReconciler) syncTemplate(rc *ReconciliationContext, targetNamespace corev1.Namespace) {
+	objs, err := rc.renderTemplate(targetNamespace)
+	if err != nil {
+		r.Log.Error(err, "Error rendering template", append(getLoggingKeysAndValuesForSyncConfig(rc.cfg), "namespace", targetNamespace.Name)...)
+		rc.markRenderFailed(targetNamespace.Name)
+		rc.IncrementFailCount(err)
+		return
+	}
+	for _, obj := range objs {
+		rc.markRendered(obj)
+		op, err := r.syncRenderedItem(rc, syncv1beta1.SyncItemOptions{}, obj)
+		r.handleSyncResult(rc, obj, op, err)
+	}
+}
+
+// handleSyncResult records the result of syncing the given rendered object in the status, metrics and events.
+func (r *SyncConfigReconciler) handleSyncResult(rc *ReconciliationContext, obj *unstructured.Unstructured, op controllerutil.OperationResult, err error) {
+	if errors.Is(err, errItemSkipped) {
+		r.Log.Info("Skipped object", append(getLoggingKeysAndValues(obj), "reason", err.Error())...)
+		rc.IncrementSkipCount()
+		rc.AddTarget(obj, syncv1beta1.TargetResultSkipped, err.Error())
+		r.observeSyncedItem(rc, obj, metricResultSkipped)
+		return
+	}
+	if err != nil {
+		r.Log.Error(err, "Error syncing object", getLoggingKeysAndValues(obj)...)
+		rc.IncrementFailCount(err)
+		rc.AddTarget(obj, syncv1beta1.TargetResultFailed, err.Error())
+		r.observeSyncedItem(rc, obj, metricResultFailed)
+		r.recordObjectEvent(rc, obj, corev1.EventTypeWarning, EventReasonFailed, "Could not sync object: "+err.Error())
+		return
+	}
+	rc.IncrementSyncCount()
+	rc.AddTarget(obj, syncv1beta1.TargetResultSynced, "")
+	r.observeSyncedItem(rc, obj, string(op))
+	r.recordChange(rc, obj, op)
+}
+
+// syncRenderedItem checks the given rendered item against overlapping SyncConfigs and the conflict policy before syncing it.
+// The given options override the settings of the SyncConfig.
+func (r *SyncConfigReconciler) syncRenderedItem(rc *ReconciliationContext, options syncv1beta1.SyncItemOptions, obj *unstructured.Unstructured) (controllerutil.OperationResult, error) {
+	ignored := rc.ignoredFields(options)
+	if err := setOwnershipMetadata(rc.cfg, obj, ignored); err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+	if err := rc.checkOverlap(obj); err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+	if err := r.checkConflictPolicy(rc, rc.conflictPolicy(options), obj); err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+	return r.syncItem(rc, obj, rc.forceRecreate(options), ignored)
+}
+
+func (r *SyncConfigReconciler) syncItem(rc *ReconciliationContext, obj *unstructured.Unstructured, force bool, ignored []ignoreDifference) (controllerutil.OperationResult, error) {
 	l := r.Log.
 		WithValues(getLoggingKeysAndValues(obj)...).
 		WithValues(getLoggingKeysAndValuesForSyncConfig(rc.cfg)...)
 	l.V(2).Info("Syncing object")
 
+	serverSideApply := rc.cfg.Spec.ApplyStrategy == syncv1beta1.ApplyStrategyServerSideApply
+	if serverSideApply {
+		// Leave the ignored fields out of the applied object, so that espejo does not take over their ownership.
+		removeIgnoredFields(ignored, obj)
+	}
+	// The drift of the synced object is only corrected for the fields of the item that are not ignored.
+	managed := obj.DeepCopy()
+	removeIgnoredFields(ignored, managed)
+	var live *unstructured.Unstructured
+	if serverSideApply || hasIgnoredFields(ignored, obj) {
+		var err error
+		if live, err = r.fetchLiveObject(rc, obj); err != nil {
+			return controllerutil.OperationResultNone, err
+		}
+	}
+	if live != nil && !serverSideApply {
+		// Copy the values of the ignored fields from the existing object, so that neither updates nor recreations overwrite them.
+		preserveIgnoredFields(ignored, obj, live)
+	}
+
+	var op controllerutil.OperationResult
+	var err error
+	if serverSideApply {
+		op, err = r.applyItem(rc, obj.DeepCopy(), live)
+	} else {
+		op, err = r.updateItem(rc, obj, l)
+	}
+
+	if apierrors.IsInvalid(err) && force {
+		err = r.recreateObject(rc, obj)
+		if err != nil {
+			return controllerutil.OperationResultNone, err
+		}
+		l.Info("Force recreated object")
+		r.recordManifest(rc, managed)
+		return operationResultRecreated, nil
+	}
+	if err == nil {
+		r.recordManifest(rc, managed)
+	}
+	return op, err
+}
+
+// recordManifest records the given manifest of a synced object in the Watcher, so that only changes to its fields are
+// corrected as drift. Nothing is recorded in dry-run mode, as the object has not been modified.
+func (r *SyncConfigReconciler) recordManifest(rc *ReconciliationContext, manifest *unstructured.Unstructured) {
+	if r.Watcher == nil || rc.dryRun {
+		return
+	}
+	r.Watcher.recordManifest(manifest)
+}
+
+// fetchLiveObject returns the existing object of the given rendered item or nil if it does not exist.
+func (r *SyncConfigReconciler) fetchLiveObject(rc *ReconciliationContext, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	return getLiveObject(rc.ctx, r.Client, obj)
+}
+
+// updateItem creates the given object or replaces all non system managed fields of an existing object.
+func (r *SyncConfigReconciler) updateItem(rc *ReconciliationContext, obj *unstructured.Unstructured, l logr.Logger) (controllerutil.OperationResult, error) {
 	found := &unstructured.Unstructured{}
 	found.SetKind(obj.GetKind())
 	found.SetAPIVersion(obj.GetAPIVersion())
 	found.SetName(obj.GetName())
 	found.SetNamespace(obj.GetNamespace())
 
-	op, err := controllerutil.CreateOrUpdate(rc.ctx, r.Client, found, func() error {
+	op, err := controllerutil.CreateOrUpdate(rc.ctx, r.itemClient(rc), found, func() error {
 		copyInto(found, obj)
 		return nil
 	})
 	if op != controllerutil.OperationResultNone {
 		l.Info("Modified object")
 	}
+	return op, err
+}
 
-	if apierrors.IsInvalid(err) && force {
-		err = r.recreateObject(rc, obj)
-		if err != nil {
-			return err
-		}
-		l.Info("Force recreated object")
+// applyItem applies the given object using server-side apply with the field manager of the SyncConfig.
+// Field ownership conflicts are returned as error unless the SyncConfig forces conflicts.
+// The given live object is the existing object, if any, and is used to determine whether the object has been modified.
+func (r *SyncConfigReconciler) applyItem(rc *ReconciliationContext, obj, live *unstructured.Unstructured) (controllerutil.OperationResult, error) {
+	if err := serverSideApply(rc.ctx, r.itemClient(rc), rc.cfg, obj); err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+	switch {
+	case live == nil:
+		return controllerutil.OperationResultCreated, nil
+	case hasDrifted(live, obj):
+		return controllerutil.OperationResultUpdated, nil
+	default:
+		return controllerutil.OperationResultNone, nil
 	}
+}
+
+// serverSideApply applies the given object with the given client using the field manager of the given SyncConfig.
+// The given object is updated with the applied object returned by the API server.
+func serverSideApply(ctx context.Context, c client.Client, cfg *syncv1beta1.SyncConfig, obj *unstructured.Unstructured) error {
+	obj.SetResourceVersion("")
+	obj.SetManagedFields(nil)
 
+	opts := []client.PatchOption{client.FieldOwner(fieldManagerFor(cfg))}
+	if cfg.Spec.ForceConflicts {
+		opts = append(opts, client.ForceOwnership)
+	}
+	err := c.Patch(ctx, obj, client.Apply, opts...)
+	if apierrors.IsConflict(err) {
+		return fmt.Errorf("field ownership conflict, set .spec.forceConflicts to take over the fields: %w", err)
+	}
 	return err
 }
 
+// watchSyncItems ensures that the kinds of the sync items are watched for drift, if a watcher is configured.
+func (r *SyncConfigReconciler) watchSyncItems(rc *ReconciliationContext) {
+	r.watchKinds(specKinds(rc.cfg.Spec))
+}
+
+// watchKinds ensures that the given kinds are watched for drift, if a watcher is configured.
+func (r *SyncConfigReconciler) watchKinds(kinds []syncv1beta1.ManagedKind) {
+	if r.Watcher == nil {
+		return
+	}
+	for _, kind := range kinds {
+		if err := r.Watcher.Watch(kind); err != nil {
+			r.Log.Error(err, "Could not watch synced objects", "APIVersion", kind.APIVersion, "Kind", kind.Kind)
+		}
+	}
+}
+
 func (r *SyncConfigReconciler) deleteItems(rc *ReconciliationContext, targetNamespace corev1.Namespace) {
-	for _, deleteItem := range rc.cfg.Spec.DeleteItems {
+	for i, deleteItem := range rc.cfg.Spec.DeleteItems {
+		if !rc.isDeleteSelected(i, targetNamespace) {
+			continue
+		}
 		r.Log.V(1).Info("Deleting", "item", deleteItem)
 		deleteObj := deleteItem.ToDeleteObj(targetNamespace.Name)
 
 		propagationPolicy := metav1.DeletePropagationBackground
-		err := r.Client.Delete(rc.ctx, deleteObj, &client.DeleteOptions{
+		err := r.itemClient(rc).Delete(rc.ctx, deleteObj, &client.DeleteOptions{
 			PropagationPolicy: &propagationPolicy,
 		})
 		if err != nil {
 			if !apierrors.IsNotFound(err) {
-				rc.IncrementDeleteCount()
+				rc.IncrementFailCount(err)
+				rc.AddTarget(deleteObj, syncv1beta1.TargetResultFailed, err.Error())
+				r.observeDeletedItem(rc, deleteObj, metricResultFailed)
+				r.recordObjectEvent(rc, deleteObj, corev1.EventTypeWarning, EventReasonFailed, "Could not delete object: "+err.Error())
 				r.Log.WithValues(getLoggingKeysAndValues(deleteObj)...).Info("Error deleting object", "error", err)
 			}
 		} else {
 			r.Log.Info("Deleted", getLoggingKeysAndValues(deleteObj)...)
 			rc.IncrementDeleteCount()
+			rc.AddTarget(deleteObj, syncv1beta1.TargetResultDeleted, "")
+			r.observeDeletedItem(rc, deleteObj, metricResultDeleted)
+			r.recordChange(rc, deleteObj, operationResultDeleted)
 		}
 	}
 	return
@@ -210,17 +471,39 @@ func (r *SyncConfigReconciler) recreateObject(rc *ReconciliationContext, obj *un
 	obj.SetResourceVersion("")
 
 	propagationPolicy := metav1.DeletePropagationForeground
-	err := r.Client.Delete(rc.ctx, obj, &client.DeleteOptions{
+	err := r.itemClient(rc).Delete(rc.ctx, obj, &client.DeleteOptions{
 		PropagationPolicy: &propagationPolicy,
 	})
 	if err != nil {
 		return err
 	}
+	if rc.dryRun {
+		// The object still exists after a dry-run deletion, creating it again would fail.
+		return nil
+	}
 
-	err = r.Client.Create(context.Background(), obj)
+	if rc.cfg.Spec.ApplyStrategy == syncv1beta1.ApplyStrategyServerSideApply {
+		_, err = r.applyItem(rc, obj, nil)
+		return err
+	}
+	err = r.itemClient(rc).Create(context.Background(), obj)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// isDryRun returns true if the given SyncConfig is to be reconciled in dry-run mode.
+func (r *SyncConfigReconciler) isDryRun(syncConfig *syncv1beta1.SyncConfig) bool {
+	return r.DryRun || syncConfig.Spec.DryRun
+}
+
+// itemClient returns the client to create, update and delete synced objects with.
+// In dry-run mode, all modifying requests are sent as server-side dry-run requests.
+func (r *SyncConfigReconciler) itemClient(rc *ReconciliationContext) client.Client {
+	if rc.dryRun {
+		return client.NewDryRunClient(r.Client)
+	}
+	return r.Client
+}
@@ -5,18 +5,21 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/suite"
+	coordinationv1 "k8s.io/api/coordination/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
-	. "github.com/vshn/espejo/api/v1alpha1"
-	syncv1alpha1 "github.com/vshn/espejo/api/v1alpha1"
+	. "github.com/vshn/espejo/api/v1beta1"
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -48,7 +51,7 @@ func (ts *SyncConfigControllerTestSuite) Test_GivenNewSyncConfig_WhenReconcile_T
 	sc := &SyncConfig{
 		ObjectMeta: metav1.ObjectMeta{Name: "test-syncconfig", Namespace: ts.NS},
 		Spec: SyncConfigSpec{
-			SyncItems:         []syncv1alpha1.Manifest{syncv1alpha1.Manifest{toUnstructured(ts.T(), cm)}},
+			SyncItems:         []syncv1beta1.SyncItem{{Manifest: syncv1beta1.Manifest{Unstructured: toUnstructured(ts.T(), cm)}}},
 			NamespaceSelector: &NamespaceSelector{MatchNames: []string{ts.NS}},
 		},
 	}
@@ -62,6 +65,11 @@ func (ts *SyncConfigControllerTestSuite) Test_GivenNewSyncConfig_WhenReconcile_T
 	cm.Namespace = ts.NS
 	ts.FetchResource(ts.MapToNamespacedName(cm), cm)
 	ts.Assert().Equal(ts.NS, cm.Data["PROJECT_NAME"])
+	ts.Assert().Equal(ManagedByEspejo, cm.Labels[LabelManagedBy])
+	ts.Assert().Equal(string(sc.UID), cm.Labels[LabelOwnerUID])
+	ts.Assert().Equal(sc.Namespace, cm.Annotations[AnnotationOwnerNamespace])
+	ts.Assert().Equal(sc.Name, cm.Annotations[AnnotationOwnerName])
+	ts.Assert().NotEmpty(cm.Annotations[AnnotationManifestHash])
 
 	ts.FetchResource(ts.MapToNamespacedName(sc), sc)
 	ts.Assert().Equal(int64(0), sc.Status.DeletedItemCount)
@@ -80,7 +88,7 @@ func (ts *SyncConfigControllerTestSuite) Test_GivenSyncConfig_WhenReconcile_Then
 	sc := &SyncConfig{
 		ObjectMeta: metav1.ObjectMeta{Name: "test-syncconfig", Namespace: ts.NS},
 		Spec: SyncConfigSpec{
-			SyncItems:         []syncv1alpha1.Manifest{syncv1alpha1.Manifest{toUnstructured(ts.T(), cm)}},
+			SyncItems:         []syncv1beta1.SyncItem{{Manifest: syncv1beta1.Manifest{Unstructured: toUnstructured(ts.T(), cm)}}},
 			NamespaceSelector: &NamespaceSelector{MatchNames: []string{ts.NS}},
 		},
 	}
@@ -127,7 +135,7 @@ func (ts *SyncConfigControllerTestSuite) Test_GivenForceSyncConfig_WhenReconcile
 	sc := &SyncConfig{
 		ObjectMeta: metav1.ObjectMeta{Name: "test-syncconfig", Namespace: ts.NS},
 		Spec: SyncConfigSpec{
-			SyncItems:         []syncv1alpha1.Manifest{syncv1alpha1.Manifest{toUnstructured(ts.T(), cm)}},
+			SyncItems:         []syncv1beta1.SyncItem{{Manifest: syncv1beta1.Manifest{Unstructured: toUnstructured(ts.T(), cm)}}},
 			NamespaceSelector: &NamespaceSelector{MatchNames: []string{ts.NS}},
 			ForceRecreate:     true,
 		},
@@ -205,6 +213,12 @@ func (ts *SyncConfigControllerTestSuite) Test_GivenInvalidConfig_WhenReconcile_T
 	ts.FetchResource(ts.MapToNamespacedName(sc), sc)
 	condition := meta.FindStatusCondition(sc.Status.Conditions, ConditionInvalid.String())
 	ts.Assert().NotNil(condition)
+	ts.Assert().Equal(sc.Generation, sc.Status.ObservedGeneration)
+	degraded := meta.FindStatusCondition(sc.Status.Conditions, ConditionDegraded.String())
+	ts.Require().NotNil(degraded)
+	ts.Assert().Equal(metav1.ConditionTrue, degraded.Status)
+	ts.Assert().Equal(SyncReasonInvalid, degraded.Reason)
+	ts.Assert().Equal(sc.Generation, degraded.ObservedGeneration)
 }
 
 type readonlyClient struct {
@@ -229,12 +243,14 @@ func (ts *SyncConfigControllerTestSuite) Test_GivenSyncConfigWithNoDiff_WhenReco
 	sc := &SyncConfig{
 		ObjectMeta: metav1.ObjectMeta{Name: "test-syncconfig", Namespace: ts.NS},
 		Spec: SyncConfigSpec{
-			SyncItems:         []syncv1alpha1.Manifest{syncv1alpha1.Manifest{toUnstructured(ts.T(), cm)}},
+			SyncItems:         []syncv1beta1.SyncItem{{Manifest: syncv1beta1.Manifest{Unstructured: toUnstructured(ts.T(), cm)}}},
 			NamespaceSelector: &NamespaceSelector{MatchNames: []string{ts.NS}},
 		},
 	}
+	ts.EnsureResources(sc)
 	cm.Namespace = ts.NS
-	ts.EnsureResources(cm, sc)
+	ts.givenOwnershipMetadata(sc, cm)
+	ts.EnsureResources(cm)
 	reconciler := &SyncConfigReconciler{
 		Client: readonlyClient{
 			ts.reconciler.Client,
@@ -257,3 +273,517 @@ func (ts *SyncConfigControllerTestSuite) Test_GivenSyncConfigWithNoDiff_WhenReco
 	ts.Assert().Equal(int64(1), sc.Status.SynchronizedItemCount)
 
 }
+
+func (ts *SyncConfigControllerTestSuite) Test_GivenTemplateWithIntegerField_WhenReconcileAgain_ThenNoOps() {
+	sc := &SyncConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-syncconfig", Namespace: ts.NS},
+		Spec: SyncConfigSpec{
+			Template: &syncv1beta1.Template{
+				Jsonnet: `[{ apiVersion: "coordination.k8s.io/v1", kind: "Lease", metadata: { name: "test-lease" }, spec: { leaseDurationSeconds: 30 } }]`,
+			},
+			NamespaceSelector: &NamespaceSelector{MatchNames: []string{ts.NS}},
+		},
+	}
+	ts.EnsureResources(sc)
+	_, err := ts.reconciler.Reconcile(ts.Ctx, ctrl.Request{NamespacedName: ts.MapToNamespacedName(sc)})
+	ts.Require().NoError(err)
+	lease := &coordinationv1.Lease{}
+	ts.FetchResource(types.NamespacedName{Namespace: ts.NS, Name: "test-lease"}, lease)
+	ts.Require().NotNil(lease.Spec.LeaseDurationSeconds)
+	ts.Assert().Equal(int32(30), *lease.Spec.LeaseDurationSeconds)
+
+	reconciler := &SyncConfigReconciler{
+		Client: readonlyClient{ts.reconciler.Client},
+		Log:    ts.reconciler.Log,
+		Scheme: ts.reconciler.Scheme,
+	}
+	_, err = reconciler.Reconcile(ts.Ctx, ctrl.Request{NamespacedName: ts.MapToNamespacedName(sc)})
+	ts.Require().NoError(err)
+
+	ts.FetchResource(ts.MapToNamespacedName(sc), sc)
+	ts.Assert().Equal(int64(0), sc.Status.FailedItemCount)
+	ts.Assert().Equal(int64(1), sc.Status.SynchronizedItemCount)
+}
+
+func (ts *SyncConfigControllerTestSuite) Test_GivenServerSideApplySyncConfig_WhenReconcile_ThenKeepFieldsOfOtherManagers() {
+	cm := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-configmap",
+		},
+		Data: map[string]string{"PROJECT_NAME": "${PROJECT_NAME}"},
+	}
+	sc := &SyncConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-syncconfig", Namespace: ts.NS},
+		Spec: SyncConfigSpec{
+			SyncItems:         []syncv1beta1.SyncItem{{Manifest: syncv1beta1.Manifest{Unstructured: toUnstructured(ts.T(), cm)}}},
+			NamespaceSelector: &NamespaceSelector{MatchNames: []string{ts.NS}},
+			ApplyStrategy:     ApplyStrategyServerSideApply,
+		},
+	}
+	cm.Namespace = ts.NS
+	cm.Data = map[string]string{"other": "new"}
+	ts.EnsureResources(cm, sc)
+	result, err := ts.reconciler.Reconcile(ts.Ctx, ctrl.Request{
+		NamespacedName: ts.MapToNamespacedName(sc),
+	})
+	ts.Require().NoError(err)
+	ts.Assert().NotNil(result)
+
+	ts.FetchResource(ts.MapToNamespacedName(cm), cm)
+	ts.Assert().Equal(ts.NS, cm.Data["PROJECT_NAME"])
+	ts.Assert().Equal("new", cm.Data["other"])
+
+	ts.FetchResource(ts.MapToNamespacedName(sc), sc)
+	ts.Assert().Equal(int64(0), sc.Status.FailedItemCount)
+	ts.Assert().Equal(int64(1), sc.Status.SynchronizedItemCount)
+}
+
+func (ts *SyncConfigControllerTestSuite) Test_GivenServerSideApplySyncConfig_WhenFieldConflicts_ThenFailItemUnlessForced() {
+	cm := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-configmap",
+		},
+		Data: map[string]string{"PROJECT_NAME": "${PROJECT_NAME}"},
+	}
+	sc := &SyncConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-syncconfig", Namespace: ts.NS},
+		Spec: SyncConfigSpec{
+			SyncItems:         []syncv1beta1.SyncItem{{Manifest: syncv1beta1.Manifest{Unstructured: toUnstructured(ts.T(), cm)}}},
+			NamespaceSelector: &NamespaceSelector{MatchNames: []string{ts.NS}},
+			ApplyStrategy:     ApplyStrategyServerSideApply,
+		},
+	}
+	cm.Namespace = ts.NS
+	cm.Data["PROJECT_NAME"] = "wrong"
+	ts.EnsureResources(cm, sc)
+	_, err := ts.reconciler.Reconcile(ts.Ctx, ctrl.Request{
+		NamespacedName: ts.MapToNamespacedName(sc),
+	})
+	ts.Require().NoError(err)
+
+	ts.FetchResource(ts.MapToNamespacedName(cm), cm)
+	ts.Assert().Equal("wrong", cm.Data["PROJECT_NAME"])
+	ts.FetchResource(ts.MapToNamespacedName(sc), sc)
+	ts.Assert().Equal(int64(1), sc.Status.FailedItemCount)
+
+	sc.Spec.ForceConflicts = true
+	ts.UpdateResources(sc)
+	_, err = ts.reconciler.Reconcile(ts.Ctx, ctrl.Request{
+		NamespacedName: ts.MapToNamespacedName(sc),
+	})
+	ts.Require().NoError(err)
+
+	ts.FetchResource(ts.MapToNamespacedName(cm), cm)
+	ts.Assert().Equal(ts.NS, cm.Data["PROJECT_NAME"])
+	ts.FetchResource(ts.MapToNamespacedName(sc), sc)
+	ts.Assert().Equal(int64(0), sc.Status.FailedItemCount)
+	ts.Assert().Equal(int64(1), sc.Status.SynchronizedItemCount)
+}
+
+// givenOwnershipMetadata adds the ownership metadata to the given ConfigMap as if it was rendered from the first sync item of the given SyncConfig.
+func (ts *SyncConfigControllerTestSuite) givenOwnershipMetadata(sc *SyncConfig, cm *corev1.ConfigMap) {
+	ts.FetchResource(ts.MapToNamespacedName(sc), sc)
+	rendered, err := (&ReconciliationContext{cfg: sc}).renderItem(sc.Spec.SyncItems[0], namespaceFromString(cm.Namespace))
+	ts.Require().NoError(err)
+	ts.Require().NoError(setOwnershipMetadata(sc, rendered, nil))
+	cm.Labels = rendered.GetLabels()
+	cm.Annotations = rendered.GetAnnotations()
+}
+
+func (ts *SyncConfigControllerTestSuite) Test_GivenPruneSyncConfig_WhenItemRemoved_ThenDeleteOwnedObjectsOnly() {
+	keep := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "keep-configmap"},
+	}
+	removed := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "removed-configmap"},
+	}
+	unmanaged := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "unmanaged-configmap", Namespace: ts.NS},
+	}
+	sc := &SyncConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-syncconfig", Namespace: ts.NS},
+		Spec: SyncConfigSpec{
+			SyncItems: []syncv1beta1.SyncItem{
+				{Manifest: syncv1beta1.Manifest{Unstructured: toUnstructured(ts.T(), keep)}},
+				{Manifest: syncv1beta1.Manifest{Unstructured: toUnstructured(ts.T(), removed)}},
+			},
+			NamespaceSelector: &NamespaceSelector{MatchNames: []string{ts.NS}},
+			Prune:             true,
+		},
+	}
+	ts.EnsureResources(unmanaged, sc)
+	_, err := ts.reconciler.Reconcile(ts.Ctx, ctrl.Request{NamespacedName: ts.MapToNamespacedName(sc)})
+	ts.Require().NoError(err)
+
+	ts.FetchResource(ts.MapToNamespacedName(sc), sc)
+	ts.Assert().Equal([]ManagedKind{{APIVersion: "v1", Kind: "ConfigMap"}}, sc.Status.ManagedKinds)
+	sc.Spec.SyncItems = sc.Spec.SyncItems[:1]
+	ts.UpdateResources(sc)
+	_, err = ts.reconciler.Reconcile(ts.Ctx, ctrl.Request{NamespacedName: ts.MapToNamespacedName(sc)})
+	ts.Require().NoError(err)
+
+	keep.Namespace = ts.NS
+	removed.Namespace = ts.NS
+	ts.Assert().True(ts.IsResourceExisting(ts.Ctx, keep))
+	ts.Assert().False(ts.IsResourceExisting(ts.Ctx, removed))
+	ts.Assert().True(ts.IsResourceExisting(ts.Ctx, unmanaged))
+
+	ts.FetchResource(ts.MapToNamespacedName(sc), sc)
+	ts.Assert().Equal(int64(1), sc.Status.DeletedItemCount)
+	ts.Assert().Equal(int64(1), sc.Status.SynchronizedItemCount)
+	ts.Assert().Equal(int64(0), sc.Status.FailedItemCount)
+}
+
+func (ts *SyncConfigControllerTestSuite) Test_GivenDeletePolicy_WhenSyncConfigDeleted_ThenDeleteSyncedObjectsBeforeReleasing() {
+	cm := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-configmap"},
+	}
+	sc := &SyncConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-syncconfig", Namespace: ts.NS},
+		Spec: SyncConfigSpec{
+			SyncItems:         []syncv1beta1.SyncItem{{Manifest: syncv1beta1.Manifest{Unstructured: toUnstructured(ts.T(), cm)}}},
+			NamespaceSelector: &NamespaceSelector{MatchNames: []string{ts.NS}},
+			DeletionPolicy:    DeletionPolicyDelete,
+		},
+	}
+	ts.EnsureResources(sc)
+	_, err := ts.reconciler.Reconcile(ts.Ctx, ctrl.Request{NamespacedName: ts.MapToNamespacedName(sc)})
+	ts.Require().NoError(err)
+
+	ts.FetchResource(ts.MapToNamespacedName(sc), sc)
+	ts.Assert().Contains(sc.Finalizers, FinalizerCleanup)
+	cm.Namespace = ts.NS
+	ts.Assert().True(ts.IsResourceExisting(ts.Ctx, cm))
+
+	ts.DeleteResources(sc)
+	result, err := ts.reconciler.Reconcile(ts.Ctx, ctrl.Request{NamespacedName: ts.MapToNamespacedName(sc)})
+	ts.Require().NoError(err)
+	ts.Assert().Equal(deletionRequeueInterval, result.RequeueAfter)
+	ts.FetchResource(ts.MapToNamespacedName(sc), sc)
+	condition := meta.FindStatusCondition(sc.Status.Conditions, ConditionConfigReady.String())
+	ts.Require().NotNil(condition)
+	ts.Assert().Equal(SyncReasonDeleting, condition.Reason)
+
+	_, err = ts.reconciler.Reconcile(ts.Ctx, ctrl.Request{NamespacedName: ts.MapToNamespacedName(sc)})
+	ts.Require().NoError(err)
+
+	ts.Assert().False(ts.IsResourceExisting(ts.Ctx, cm))
+	ts.Assert().False(ts.IsResourceExisting(ts.Ctx, sc))
+}
+
+func (ts *SyncConfigControllerTestSuite) Test_GivenDeletePolicy_WhenSyncConfigDeletedInDryRun_ThenKeepFinalizerAndObjects() {
+	cm := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-configmap"},
+	}
+	sc := &SyncConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-syncconfig", Namespace: ts.NS},
+		Spec: SyncConfigSpec{
+			SyncItems:         []syncv1beta1.SyncItem{{Manifest: syncv1beta1.Manifest{Unstructured: toUnstructured(ts.T(), cm)}}},
+			NamespaceSelector: &NamespaceSelector{MatchNames: []string{ts.NS}},
+			DeletionPolicy:    DeletionPolicyDelete,
+		},
+	}
+	ts.EnsureResources(sc)
+	_, err := ts.reconciler.Reconcile(ts.Ctx, ctrl.Request{NamespacedName: ts.MapToNamespacedName(sc)})
+	ts.Require().NoError(err)
+
+	ts.reconciler.DryRun = true
+	ts.DeleteResources(sc)
+	_, err = ts.reconciler.Reconcile(ts.Ctx, ctrl.Request{NamespacedName: ts.MapToNamespacedName(sc)})
+	ts.Require().NoError(err)
+
+	cm.Namespace = ts.NS
+	ts.Assert().True(ts.IsResourceExisting(ts.Ctx, cm))
+	ts.FetchResource(ts.MapToNamespacedName(sc), sc)
+	ts.Assert().Contains(sc.Finalizers, FinalizerCleanup)
+	ts.Require().NotNil(sc.Status.DryRun)
+	ts.Assert().Equal(int64(1), sc.Status.DryRun.WouldDelete)
+	condition := meta.FindStatusCondition(sc.Status.Conditions, ConditionConfigReady.String())
+	ts.Require().NotNil(condition)
+	ts.Assert().Equal(SyncReasonDeleting, condition.Reason)
+
+	ts.reconciler.DryRun = false
+	_, err = ts.reconciler.Reconcile(ts.Ctx, ctrl.Request{NamespacedName: ts.MapToNamespacedName(sc)})
+	ts.Require().NoError(err)
+	_, err = ts.reconciler.Reconcile(ts.Ctx, ctrl.Request{NamespacedName: ts.MapToNamespacedName(sc)})
+	ts.Require().NoError(err)
+
+	ts.Assert().False(ts.IsResourceExisting(ts.Ctx, cm))
+	ts.Assert().False(ts.IsResourceExisting(ts.Ctx, sc))
+}
+
+func (ts *SyncConfigControllerTestSuite) Test_GivenConflictPolicy_WhenUnmanagedObjectExists_ThenLeaveObjectUntouched() {
+	for _, policy := range []ConflictPolicy{ConflictPolicySkip, ConflictPolicyFail} {
+		cm := &corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "configmap-" + strings.ToLower(string(policy))},
+			Data:       map[string]string{"PROJECT_NAME": "${PROJECT_NAME}"},
+		}
+		sc := &SyncConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "syncconfig-" + strings.ToLower(string(policy)), Namespace: ts.NS},
+			Spec: SyncConfigSpec{
+				SyncItems:         []syncv1beta1.SyncItem{{Manifest: syncv1beta1.Manifest{Unstructured: toUnstructured(ts.T(), cm)}}},
+				NamespaceSelector: &NamespaceSelector{MatchNames: []string{ts.NS}},
+				ConflictPolicy:    policy,
+			},
+		}
+		cm.Namespace = ts.NS
+		cm.Data["PROJECT_NAME"] = "hand-crafted"
+		ts.EnsureResources(cm, sc)
+		_, err := ts.reconciler.Reconcile(ts.Ctx, ctrl.Request{NamespacedName: ts.MapToNamespacedName(sc)})
+		ts.Require().NoError(err)
+
+		ts.FetchResource(ts.MapToNamespacedName(cm), cm)
+		ts.Assert().Equal("hand-crafted", cm.Data["PROJECT_NAME"])
+		ts.Assert().NotContains(cm.Labels, LabelManagedBy)
+
+		ts.FetchResource(ts.MapToNamespacedName(sc), sc)
+		ts.Assert().Equal(int64(0), sc.Status.SynchronizedItemCount)
+		ts.Assert().Equal(int64(1), sc.Status.MatchedNamespaceCount)
+		ts.Require().Len(sc.Status.Targets, 1)
+		ts.Assert().Equal(TargetReference{Namespace: ts.NS, APIVersion: "v1", Kind: "ConfigMap", Name: cm.Name}, sc.Status.Targets[0].TargetReference)
+		ts.Assert().NotEmpty(sc.Status.Targets[0].Message)
+		if policy == ConflictPolicySkip {
+			ts.Assert().Equal(int64(1), sc.Status.SkippedItemCount)
+			ts.Assert().Equal(int64(0), sc.Status.FailedItemCount)
+			ts.Assert().Equal(TargetResultSkipped, sc.Status.Targets[0].Result)
+		} else {
+			ts.Assert().Equal(int64(0), sc.Status.SkippedItemCount)
+			ts.Assert().Equal(int64(1), sc.Status.FailedItemCount)
+			ts.Assert().Equal(TargetResultFailed, sc.Status.Targets[0].Result)
+		}
+	}
+}
+
+func (ts *SyncConfigControllerTestSuite) Test_GivenItemConflictPolicy_WhenUnmanagedObjectExists_ThenOverrideSpec() {
+	cm := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "configmap-item-options"},
+		Data:       map[string]string{"PROJECT_NAME": "${PROJECT_NAME}"},
+	}
+	sc := &SyncConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "syncconfig-item-options", Namespace: ts.NS},
+		Spec: SyncConfigSpec{
+			SyncItems: []SyncItem{{
+				Manifest: Manifest{Unstructured: toUnstructured(ts.T(), cm)},
+				Options:  SyncItemOptions{ConflictPolicy: ConflictPolicySkip},
+			}},
+			NamespaceSelector: &NamespaceSelector{MatchNames: []string{ts.NS}},
+			ConflictPolicy:    ConflictPolicyAdopt,
+		},
+	}
+	cm.Namespace = ts.NS
+	cm.Data["PROJECT_NAME"] = "hand-crafted"
+	ts.EnsureResources(cm, sc)
+	_, err := ts.reconciler.Reconcile(ts.Ctx, ctrl.Request{NamespacedName: ts.MapToNamespacedName(sc)})
+	ts.Require().NoError(err)
+
+	ts.FetchResource(ts.MapToNamespacedName(cm), cm)
+	ts.Assert().Equal("hand-crafted", cm.Data["PROJECT_NAME"])
+	ts.FetchResource(ts.MapToNamespacedName(sc), sc)
+	ts.Assert().Equal(int64(1), sc.Status.SkippedItemCount)
+	ts.Assert().Equal(int64(0), sc.Status.SynchronizedItemCount)
+}
+
+func (ts *SyncConfigControllerTestSuite) Test_GivenOverlappingSyncConfigs_WhenReconcile_ThenHigherPriorityWins() {
+	cm := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "shared-configmap"},
+		Data:       map[string]string{"owner": "low"},
+	}
+	low := &SyncConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "low-priority", Namespace: ts.NS},
+		Spec: SyncConfigSpec{
+			SyncItems:         []syncv1beta1.SyncItem{{Manifest: syncv1beta1.Manifest{Unstructured: toUnstructured(ts.T(), cm)}}},
+			NamespaceSelector: &NamespaceSelector{MatchNames: []string{ts.NS}},
+		},
+	}
+	cm.Data["owner"] = "high"
+	high := &SyncConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "high-priority", Namespace: ts.NS},
+		Spec: SyncConfigSpec{
+			SyncItems:         []syncv1beta1.SyncItem{{Manifest: syncv1beta1.Manifest{Unstructured: toUnstructured(ts.T(), cm)}}},
+			NamespaceSelector: &NamespaceSelector{MatchNames: []string{ts.NS}},
+			Priority:          10,
+		},
+	}
+	ts.EnsureResources(low, high)
+	for _, sc := range []*SyncConfig{high, low} {
+		_, err := ts.reconciler.Reconcile(ts.Ctx, ctrl.Request{NamespacedName: ts.MapToNamespacedName(sc)})
+		ts.Require().NoError(err)
+	}
+
+	cm.Namespace = ts.NS
+	ts.FetchResource(ts.MapToNamespacedName(cm), cm)
+	ts.Assert().Equal("high", cm.Data["owner"])
+
+	ts.FetchResource(ts.MapToNamespacedName(low), low)
+	ts.Assert().Equal(int64(1), low.Status.SkippedItemCount)
+	condition := meta.FindStatusCondition(low.Status.Conditions, ConditionConflict.String())
+	ts.Require().NotNil(condition)
+	ts.Assert().Equal(metav1.ConditionTrue, condition.Status)
+	ts.Assert().Contains(condition.Message, high.Name)
+
+	ts.FetchResource(ts.MapToNamespacedName(high), high)
+	ts.Assert().Equal(int64(1), high.Status.SynchronizedItemCount)
+	condition = meta.FindStatusCondition(high.Status.Conditions, ConditionConflict.String())
+	ts.Require().NotNil(condition)
+	ts.Assert().Contains(condition.Message, low.Name)
+}
+
+func (ts *SyncConfigControllerTestSuite) Test_GivenIgnoreDifferences_WhenReconcile_ThenPreserveExistingValues() {
+	cm := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-configmap"},
+		Data:       map[string]string{"PROJECT_NAME": "${PROJECT_NAME}", "replicas": "1"},
+	}
+	sc := &SyncConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-syncconfig", Namespace: ts.NS},
+		Spec: SyncConfigSpec{
+			SyncItems:         []syncv1beta1.SyncItem{{Manifest: syncv1beta1.Manifest{Unstructured: toUnstructured(ts.T(), cm)}}},
+			NamespaceSelector: &NamespaceSelector{MatchNames: []string{ts.NS}},
+			IgnoreDifferences: []IgnoreDifference{{
+				Kind:         "ConfigMap",
+				Name:         cm.Name,
+				JSONPointers: []string{"/data/replicas"},
+				JSONPaths:    []string{".metadata.annotations['example.com/injected']"},
+			}},
+		},
+	}
+	cm.Namespace = ts.NS
+	cm.Annotations = map[string]string{"example.com/injected": "true"}
+	cm.Data["replicas"] = "5"
+	ts.EnsureResources(cm, sc)
+	_, err := ts.reconciler.Reconcile(ts.Ctx, ctrl.Request{NamespacedName: ts.MapToNamespacedName(sc)})
+	ts.Require().NoError(err)
+
+	ts.FetchResource(ts.MapToNamespacedName(cm), cm)
+	ts.Assert().Equal(ts.NS, cm.Data["PROJECT_NAME"])
+	ts.Assert().Equal("5", cm.Data["replicas"])
+	ts.Assert().Equal("true", cm.Annotations["example.com/injected"])
+}
+
+func (ts *SyncConfigControllerTestSuite) Test_GivenDryRun_WhenReconcile_ThenReportChangesWithoutModifyingObjects() {
+	cm := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-configmap"},
+		Data:       map[string]string{"PROJECT_NAME": "${PROJECT_NAME}"},
+	}
+	sc := &SyncConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-syncconfig", Namespace: ts.NS},
+		Spec: SyncConfigSpec{
+			SyncItems:         []syncv1beta1.SyncItem{{Manifest: syncv1beta1.Manifest{Unstructured: toUnstructured(ts.T(), cm)}}},
+			NamespaceSelector: &NamespaceSelector{MatchNames: []string{ts.NS}},
+			DeletionPolicy:    DeletionPolicyDelete,
+			DryRun:            true,
+		},
+	}
+	ts.EnsureResources(sc)
+	_, err := ts.reconciler.Reconcile(ts.Ctx, ctrl.Request{NamespacedName: ts.MapToNamespacedName(sc)})
+	ts.Require().NoError(err)
+
+	cm.Namespace = ts.NS
+	ts.Assert().False(ts.IsResourceExisting(ts.Ctx, cm))
+	ts.FetchResource(ts.MapToNamespacedName(sc), sc)
+	ts.Assert().Empty(sc.Finalizers)
+	ts.Require().NotNil(sc.Status.DryRun)
+	ts.Assert().Equal(int64(1), sc.Status.DryRun.WouldCreate)
+	ts.Assert().Equal([]NamespaceDryRunStatus{{Namespace: ts.NS, WouldCreate: 1}}, sc.Status.DryRun.Namespaces)
+}
+
+func (ts *SyncConfigControllerTestSuite) Test_GivenSyncedConfig_WhenDiff_ThenReportChangedItemsOnly() {
+	cm := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-configmap"},
+		Data:       map[string]string{"PROJECT_NAME": "${PROJECT_NAME}"},
+	}
+	sc := &SyncConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-syncconfig", Namespace: ts.NS},
+		Spec: SyncConfigSpec{
+			SyncItems:         []syncv1beta1.SyncItem{{Manifest: syncv1beta1.Manifest{Unstructured: toUnstructured(ts.T(), cm)}}},
+			NamespaceSelector: &NamespaceSelector{MatchNames: []string{ts.NS}},
+		},
+	}
+	ts.EnsureResources(sc)
+	_, err := ts.reconciler.Reconcile(ts.Ctx, ctrl.Request{NamespacedName: ts.MapToNamespacedName(sc)})
+	ts.Require().NoError(err)
+
+	desired := &SyncConfig{ObjectMeta: metav1.ObjectMeta{Name: sc.Name, Namespace: sc.Namespace}, Spec: *sc.Spec.DeepCopy()}
+	diffs, err := DiffSyncConfig(ts.Ctx, ts.Client, desired, "")
+	ts.Require().NoError(err)
+	ts.Assert().Empty(diffs)
+
+	cm.Data["PROJECT_NAME"] = "changed"
+	desired.Spec.SyncItems = []syncv1beta1.SyncItem{{Manifest: syncv1beta1.Manifest{Unstructured: toUnstructured(ts.T(), cm)}}}
+	diffs, err = DiffSyncConfig(ts.Ctx, ts.Client, desired, "")
+	ts.Require().NoError(err)
+	ts.Require().Len(diffs, 1)
+	ts.Require().NotNil(diffs[0].Live)
+	ts.Assert().Equal(map[string]interface{}{"PROJECT_NAME": ts.NS}, diffs[0].Live.Object["data"])
+	ts.Assert().Equal(map[string]interface{}{"PROJECT_NAME": "changed"}, diffs[0].Desired.Object["data"])
+}
+
+func (ts *SyncConfigControllerTestSuite) Test_GivenServerSideApply_WhenDiff_ThenReportChangedItemsOnly() {
+	cm := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-configmap-ssa-diff"},
+		Data:       map[string]string{"PROJECT_NAME": "${PROJECT_NAME}"},
+	}
+	sc := &SyncConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-syncconfig-ssa-diff", Namespace: ts.NS},
+		Spec: SyncConfigSpec{
+			SyncItems:         []syncv1beta1.SyncItem{{Manifest: syncv1beta1.Manifest{Unstructured: toUnstructured(ts.T(), cm)}}},
+			NamespaceSelector: &NamespaceSelector{MatchNames: []string{ts.NS}},
+			ApplyStrategy:     ApplyStrategyServerSideApply,
+		},
+	}
+	ts.EnsureResources(sc)
+	_, err := ts.reconciler.Reconcile(ts.Ctx, ctrl.Request{NamespacedName: ts.MapToNamespacedName(sc)})
+	ts.Require().NoError(err)
+
+	desired := &SyncConfig{ObjectMeta: metav1.ObjectMeta{Name: sc.Name, Namespace: sc.Namespace}, Spec: *sc.Spec.DeepCopy()}
+	diffs, err := DiffSyncConfig(ts.Ctx, ts.Client, desired, "")
+	ts.Require().NoError(err)
+	ts.Assert().Empty(diffs)
+
+	cm.Data["PROJECT_NAME"] = "changed"
+	desired.Spec.SyncItems = []syncv1beta1.SyncItem{{Manifest: syncv1beta1.Manifest{Unstructured: toUnstructured(ts.T(), cm)}}}
+	diffs, err = DiffSyncConfig(ts.Ctx, ts.Client, desired, "")
+	ts.Require().NoError(err)
+	ts.Require().Len(diffs, 1)
+	ts.Assert().Equal(map[string]interface{}{"PROJECT_NAME": "changed"}, diffs[0].Desired.Object["data"])
+
+	cm.Namespace = ts.NS
+	ts.FetchResource(ts.MapToNamespacedName(cm), cm)
+	ts.Assert().Equal(ts.NS, cm.Data["PROJECT_NAME"])
+}
+
+func (ts *SyncConfigControllerTestSuite) Test_GivenConflictPolicySkip_WhenDiff_ThenLeaveOutUnmanagedObject() {
+	cm := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "configmap-skip-diff"},
+		Data:       map[string]string{"PROJECT_NAME": "${PROJECT_NAME}"},
+	}
+	desired := &SyncConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "syncconfig-skip-diff", Namespace: ts.NS},
+		Spec: SyncConfigSpec{
+			SyncItems:         []syncv1beta1.SyncItem{{Manifest: syncv1beta1.Manifest{Unstructured: toUnstructured(ts.T(), cm)}}},
+			NamespaceSelector: &NamespaceSelector{MatchNames: []string{ts.NS}},
+			ConflictPolicy:    ConflictPolicySkip,
+		},
+	}
+	cm.Namespace = ts.NS
+	cm.Data["PROJECT_NAME"] = "hand-crafted"
+	ts.EnsureResources(cm)
+
+	diffs, err := DiffSyncConfig(ts.Ctx, ts.Client, desired, "")
+	ts.Require().NoError(err)
+	ts.Assert().Empty(diffs)
+}
@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
+)
+
+// deletionRequeueInterval is the interval in which a deleted SyncConfig is reconciled while its synced objects are being deleted.
+const deletionRequeueInterval = 5 * time.Second
+
+// ensureFinalizer adds the cleanup finalizer to SyncConfigs with the "Delete" deletion policy and removes it from all others.
+// SyncConfigs in dry-run mode are left untouched, an existing finalizer is kept until dry run is disabled.
+func (r *SyncConfigReconciler) ensureFinalizer(ctx context.Context, syncConfig *syncv1beta1.SyncConfig) error {
+	if r.isDryRun(syncConfig) {
+		return nil
+	}
+	var changed bool
+	if syncConfig.Spec.DeletionPolicy == syncv1beta1.DeletionPolicyDelete {
+		changed = controllerutil.AddFinalizer(syncConfig, syncv1beta1.FinalizerCleanup)
+	} else {
+		changed = controllerutil.RemoveFinalizer(syncConfig, syncv1beta1.FinalizerCleanup)
+	}
+	if !changed {
+		return nil
+	}
+	return r.Client.Update(ctx, syncConfig)
+}
+
+// reconcileDeletion deletes all objects synced by the given deleted SyncConfig from all namespaces, if its deletion policy
+// demands it, and releases the cleanup finalizer once no synced objects are left.
+// In dry-run mode, the objects that would be deleted are reported and the finalizer is kept until dry run is disabled.
+func (r *SyncConfigReconciler) reconcileDeletion(ctx context.Context, syncConfig *syncv1beta1.SyncConfig) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(syncConfig, syncv1beta1.FinalizerCleanup) {
+		return ctrl.Result{}, nil
+	}
+	rc := &ReconciliationContext{
+		ctx:          ctx,
+		cfg:          syncConfig,
+		managedKinds: mergeManagedKinds(syncConfig.Status.ManagedKinds, specKinds(syncConfig.Spec)),
+		dryRun:       r.isDryRun(syncConfig),
+	}
+
+	if syncConfig.Spec.DeletionPolicy == syncv1beta1.DeletionPolicyDelete && rc.dryRun {
+		r.Log.Info("Keeping finalizer of SyncConfig in dry-run mode", getLoggingKeysAndValuesForSyncConfig(syncConfig)...)
+		r.deleteOwnedObjects(rc)
+		r.recordEvents(rc)
+		rc.SetStatusCondition(CreateStatusConditionDeletionDryRun(rc.deleteCount))
+		return ctrl.Result{RequeueAfter: r.ReconcileInterval}, r.updateStatus(rc)
+	}
+	if syncConfig.Spec.DeletionPolicy == syncv1beta1.DeletionPolicyDelete {
+		r.Log.Info("Deleting synced objects", getLoggingKeysAndValuesForSyncConfig(syncConfig)...)
+		remaining := r.deleteOwnedObjects(rc)
+		r.recordEvents(rc)
+		if remaining > 0 || rc.failCount > 0 {
+			rc.SetStatusCondition(CreateStatusConditionDeleting(remaining))
+			rc.SetStatusCondition(CreateStatusConditionProgressing(true, syncv1beta1.SyncReasonDeleting,
+				fmt.Sprintf("Deleting %d remaining synced objects", remaining)))
+			return ctrl.Result{RequeueAfter: deletionRequeueInterval}, r.updateStatus(rc)
+		}
+	}
+
+	controllerutil.RemoveFinalizer(syncConfig, syncv1beta1.FinalizerCleanup)
+	if err := r.Client.Update(ctx, syncConfig); err != nil {
+		r.Log.Error(err, "Could not remove finalizer from SyncConfig.", getLoggingKeysAndValuesForSyncConfig(syncConfig)...)
+		return ctrl.Result{}, err
+	}
+	r.Log.Info("Released SyncConfig", getLoggingKeysAndValuesForSyncConfig(syncConfig)...)
+	return ctrl.Result{}, nil
+}
+
+// deleteOwnedObjects deletes all objects of the managed kinds that carry the ownership labels of the SyncConfig.
+// It returns the number of objects that still existed, the deletion of these objects needs to be verified in a later call.
+func (r *SyncConfigReconciler) deleteOwnedObjects(rc *ReconciliationContext) int64 {
+	var remaining int64
+	for _, kind := range rc.managedKinds {
+		objs, err := r.listOwnedObjects(rc, kind)
+		if meta.IsNoMatchError(err) {
+			continue
+		}
+		if err != nil {
+			r.Log.Error(err, "Could not list synced objects", "APIVersion", kind.APIVersion, "Kind", kind.Kind)
+			rc.IncrementFailCount(err)
+			continue
+		}
+		for i := range objs {
+			obj := &objs[i]
+			remaining++
+			if obj.GetDeletionTimestamp() != nil {
+				continue
+			}
+			if err := r.pruneObject(rc, obj); err != nil {
+				r.Log.Error(err, "Could not delete synced object", getLoggingKeysAndValues(obj)...)
+				rc.IncrementFailCount(err)
+				rc.AddTarget(obj, syncv1beta1.TargetResultFailed, err.Error())
+				r.observeDeletedItem(rc, obj, metricResultFailed)
+				r.recordObjectEvent(rc, obj, corev1.EventTypeWarning, EventReasonFailed, "Could not delete object: "+err.Error())
+				continue
+			}
+			rc.IncrementDeleteCount()
+			rc.AddTarget(obj, syncv1beta1.TargetResultDeleted, "")
+			r.observeDeletedItem(rc, obj, metricResultDeleted)
+			r.recordChange(rc, obj, operationResultDeleted)
+		}
+	}
+	return remaining
+}
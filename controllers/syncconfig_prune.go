@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
+)
+
+// pruneItems deletes objects owned by the SyncConfig that are no longer rendered from the sync items from the matched
+// namespaces (if pruning is enabled) and from namespaces that are not matched anymore (if cleanup of unmatched namespaces is enabled).
+// It also updates the managed kinds of the ReconciliationContext: Kinds of which no objects remain are dropped.
+func (r *SyncConfigReconciler) pruneItems(rc *ReconciliationContext, namespaces, matchedNamespaces []corev1.Namespace) {
+	kinds := mergeManagedKinds(mergeManagedKinds(rc.managedKinds, specKinds(rc.cfg.Spec)), rc.renderedKinds)
+	if !rc.cfg.Spec.Prune && !rc.cfg.Spec.CleanupUnmatchedNamespaces {
+		rc.managedKinds = kinds
+		return
+	}
+
+	phases := make(map[string]corev1.NamespacePhase, len(namespaces))
+	for _, ns := range namespaces {
+		phases[ns.Name] = ns.Status.Phase
+	}
+	matched := make(map[string]bool, len(matchedNamespaces))
+	for _, ns := range matchedNamespaces {
+		matched[ns.Name] = true
+	}
+
+	remaining := mergeManagedKinds(specKinds(rc.cfg.Spec), rc.renderedKinds)
+	for _, kind := range kinds {
+		objs, err := r.listOwnedObjects(rc, kind)
+		if meta.IsNoMatchError(err) {
+			// The kind is not served anymore, there cannot be any objects left.
+			continue
+		}
+		if err != nil {
+			r.Log.Error(err, "Could not list objects to prune", "APIVersion", kind.APIVersion, "Kind", kind.Kind)
+			rc.IncrementFailCount(err)
+			remaining = mergeManagedKinds(remaining, []syncv1beta1.ManagedKind{kind})
+			continue
+		}
+		for i := range objs {
+			obj := &objs[i]
+			if !rc.shouldPrune(obj, phases[obj.GetNamespace()], matched[obj.GetNamespace()]) {
+				remaining = mergeManagedKinds(remaining, []syncv1beta1.ManagedKind{kind})
+				continue
+			}
+			if err := r.pruneObject(rc, obj); err != nil {
+				r.Log.Error(err, "Could not prune object", getLoggingKeysAndValues(obj)...)
+				rc.IncrementFailCount(err)
+				rc.AddTarget(obj, syncv1beta1.TargetResultFailed, err.Error())
+				r.observeDeletedItem(rc, obj, metricResultFailed)
+				r.recordObjectEvent(rc, obj, corev1.EventTypeWarning, EventReasonFailed, "Could not delete object: "+err.Error())
+				remaining = mergeManagedKinds(remaining, []syncv1beta1.ManagedKind{kind})
+				continue
+			}
+			rc.IncrementDeleteCount()
+			rc.AddTarget(obj, syncv1beta1.TargetResultDeleted, "")
+			r.observeDeletedItem(rc, obj, metricResultDeleted)
+			r.recordChange(rc, obj, operationResultDeleted)
+		}
+	}
+	rc.managedKinds = remaining
+}
+
+// shouldPrune returns true if the given owned object should be deleted.
+// Objects in namespaces that are not active or for which the template could not be rendered are never deleted.
+func (rc *ReconciliationContext) shouldPrune(obj *unstructured.Unstructured, phase corev1.NamespacePhase, matched bool) bool {
+	if phase != corev1.NamespaceActive {
+		return false
+	}
+	if matched && rc.renderFailedNamespaces[obj.GetNamespace()] {
+		return false
+	}
+	if matched {
+		return rc.cfg.Spec.Prune && !rc.isRendered(obj)
+	}
+	return rc.cfg.Spec.CleanupUnmatchedNamespaces
+}
+
+// listOwnedObjects lists all objects of the given kind that carry the ownership labels of the SyncConfig.
+// The list is limited to the namespace scope of the reconciler, if set.
+func (r *SyncConfigReconciler) listOwnedObjects(rc *ReconciliationContext, kind syncv1beta1.ManagedKind) ([]unstructured.Unstructured, error) {
+	gv, err := schema.ParseGroupVersion(kind.APIVersion)
+	if err != nil {
+		return nil, err
+	}
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gv.WithKind(kind.Kind + "List"))
+
+	opts := []client.ListOption{ownedBy(rc.cfg)}
+	if r.NamespaceScope != "" {
+		opts = append(opts, client.InNamespace(r.NamespaceScope))
+	}
+	if err := r.Client.List(rc.ctx, list, opts...); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+func (r *SyncConfigReconciler) pruneObject(rc *ReconciliationContext, obj *unstructured.Unstructured) error {
+	propagationPolicy := metav1.DeletePropagationBackground
+	err := r.itemClient(rc).Delete(rc.ctx, obj, &client.DeleteOptions{
+		PropagationPolicy: &propagationPolicy,
+	})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err == nil {
+		r.Log.Info("Pruned", getLoggingKeysAndValues(obj)...)
+	}
+	return err
+}
+
+// markRendered records the given object as part of the rendered sync items of its namespace.
+func (rc *ReconciliationContext) markRendered(obj *unstructured.Unstructured) {
+	namespace := obj.GetNamespace()
+	if rc.renderedItems[namespace] == nil {
+		rc.renderedItems[namespace] = map[string]bool{}
+	}
+	rc.renderedItems[namespace][renderedItemKey(obj)] = true
+	if obj.GetKind() != "" {
+		rc.renderedKinds = mergeManagedKinds(rc.renderedKinds, []syncv1beta1.ManagedKind{{APIVersion: obj.GetAPIVersion(), Kind: obj.GetKind()}})
+	}
+}
+
+// markRenderFailed records that the template or a sync item could not be rendered for the given namespace.
+func (rc *ReconciliationContext) markRenderFailed(namespace string) {
+	if rc.renderFailedNamespaces == nil {
+		rc.renderFailedNamespaces = map[string]bool{}
+	}
+	rc.renderFailedNamespaces[namespace] = true
+}
+
+// isRendered returns true if the given object is part of the rendered sync items of its namespace.
+func (rc *ReconciliationContext) isRendered(obj *unstructured.Unstructured) bool {
+	return rc.renderedItems[obj.GetNamespace()][renderedItemKey(obj)]
+}
+
+// renderedItemKey identifies an object within a namespace regardless of the API version.
+func renderedItemKey(obj *unstructured.Unstructured) string {
+	gvk := obj.GroupVersionKind()
+	return gvk.Group + "/" + gvk.Kind + "/" + obj.GetName()
+}
+
+// specKinds returns the distinct kinds of the sync items in the given spec.
+func specKinds(spec syncv1beta1.SyncConfigSpec) []syncv1beta1.ManagedKind {
+	kinds := make([]syncv1beta1.ManagedKind, 0, len(spec.SyncItems))
+	for _, item := range spec.SyncItems {
+		if item.Manifest.GetKind() == "" {
+			continue
+		}
+		kinds = mergeManagedKinds(kinds, []syncv1beta1.ManagedKind{{APIVersion: item.Manifest.GetAPIVersion(), Kind: item.Manifest.GetKind()}})
+	}
+	return kinds
+}
+
+// mergeManagedKinds returns the union of the given kinds, preserving the order of first occurrence.
+func mergeManagedKinds(kinds []syncv1beta1.ManagedKind, other []syncv1beta1.ManagedKind) []syncv1beta1.ManagedKind {
+	merged := make([]syncv1beta1.ManagedKind, 0, len(kinds)+len(other))
+	seen := map[syncv1beta1.ManagedKind]bool{}
+	for _, kind := range append(append([]syncv1beta1.ManagedKind{}, kinds...), other...) {
+		if seen[kind] {
+			continue
+		}
+		seen[kind] = true
+		merged = append(merged, kind)
+	}
+	return merged
+}
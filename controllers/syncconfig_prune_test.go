@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
+)
+
+func Test_MergeManagedKinds(t *testing.T) {
+	configMap := syncv1beta1.ManagedKind{APIVersion: "v1", Kind: "ConfigMap"}
+	secret := syncv1beta1.ManagedKind{APIVersion: "v1", Kind: "Secret"}
+	role := syncv1beta1.ManagedKind{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "Role"}
+
+	merged := mergeManagedKinds([]syncv1beta1.ManagedKind{configMap, secret}, []syncv1beta1.ManagedKind{secret, role, role})
+
+	assert.Equal(t, []syncv1beta1.ManagedKind{configMap, secret, role}, merged)
+}
+
+func Test_SpecKinds(t *testing.T) {
+	spec := syncv1beta1.SyncConfigSpec{
+		SyncItems: []syncv1beta1.SyncItem{
+			{Manifest: syncv1beta1.Manifest{Unstructured: toUnstructured(t, &corev1.ConfigMap{TypeMeta: metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"}, ObjectMeta: toObjectMeta("first", "")})}},
+			{Manifest: syncv1beta1.Manifest{Unstructured: toUnstructured(t, &corev1.ConfigMap{TypeMeta: metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"}, ObjectMeta: toObjectMeta("second", "")})}},
+		},
+	}
+
+	assert.Equal(t, []syncv1beta1.ManagedKind{{APIVersion: "v1", Kind: "ConfigMap"}}, specKinds(spec))
+}
+
+func Test_ReconciliationContext_IsRendered(t *testing.T) {
+	rc := &ReconciliationContext{renderedItems: map[string]map[string]bool{}}
+	rendered := toUnstructured(t, &corev1.ConfigMap{TypeMeta: metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"}, ObjectMeta: toObjectMeta("rendered", "ns")})
+	other := toUnstructured(t, &corev1.ConfigMap{TypeMeta: metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"}, ObjectMeta: toObjectMeta("rendered", "other-ns")})
+
+	rc.markRendered(&rendered)
+
+	assert.True(t, rc.isRendered(&rendered))
+	assert.False(t, rc.isRendered(&other))
+	assert.Equal(t, []syncv1beta1.ManagedKind{{APIVersion: "v1", Kind: "ConfigMap"}}, rc.renderedKinds)
+}
+
+func Test_ReconciliationContext_ShouldPrune(t *testing.T) {
+	rendered := toUnstructured(t, &corev1.ConfigMap{TypeMeta: metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"}, ObjectMeta: toObjectMeta("rendered", "ns")})
+	removed := toUnstructured(t, &corev1.ConfigMap{TypeMeta: metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"}, ObjectMeta: toObjectMeta("removed", "ns")})
+	tests := map[string]struct {
+		spec         syncv1beta1.SyncConfigSpec
+		obj          unstructured.Unstructured
+		phase        corev1.NamespacePhase
+		matched      bool
+		renderFailed bool
+		expected     bool
+	}{
+		"GivenPrune_WhenItemRemoved_ThenPrune": {
+			spec: syncv1beta1.SyncConfigSpec{Prune: true}, obj: removed, phase: corev1.NamespaceActive, matched: true, expected: true,
+		},
+		"GivenPrune_WhenItemRendered_ThenKeep": {
+			spec: syncv1beta1.SyncConfigSpec{Prune: true}, obj: rendered, phase: corev1.NamespaceActive, matched: true, expected: false,
+		},
+		"GivenPrune_WhenTemplateRenderFailed_ThenKeep": {
+			spec: syncv1beta1.SyncConfigSpec{Prune: true}, obj: removed, phase: corev1.NamespaceActive, matched: true, renderFailed: true, expected: false,
+		},
+		"GivenNoPrune_WhenItemRemoved_ThenKeep": {
+			spec: syncv1beta1.SyncConfigSpec{}, obj: removed, phase: corev1.NamespaceActive, matched: true, expected: false,
+		},
+		"GivenCleanupUnmatched_WhenNamespaceUnmatched_ThenPrune": {
+			spec: syncv1beta1.SyncConfigSpec{CleanupUnmatchedNamespaces: true}, obj: rendered, phase: corev1.NamespaceActive, matched: false, expected: true,
+		},
+		"GivenNoCleanupUnmatched_WhenNamespaceUnmatched_ThenKeep": {
+			spec: syncv1beta1.SyncConfigSpec{Prune: true}, obj: removed, phase: corev1.NamespaceActive, matched: false, expected: false,
+		},
+		"GivenCleanupUnmatched_WhenNamespaceTerminating_ThenKeep": {
+			spec: syncv1beta1.SyncConfigSpec{Prune: true, CleanupUnmatchedNamespaces: true}, obj: removed, phase: corev1.NamespaceTerminating, matched: false, expected: false,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			rc := &ReconciliationContext{
+				cfg:           &syncv1beta1.SyncConfig{Spec: tt.spec},
+				renderedItems: map[string]map[string]bool{},
+			}
+			rc.markRendered(&rendered)
+			if tt.renderFailed {
+				rc.markRenderFailed("ns")
+			}
+			assert.Equal(t, tt.expected, rc.shouldPrune(&tt.obj, tt.phase, tt.matched))
+		})
+	}
+}
+
+func Test_ReconciliationContext_RenderSyncItem_GivenRenderFailure_ThenKeepExistingObjects(t *testing.T) {
+	existing := toUnstructured(t, &corev1.ConfigMap{TypeMeta: metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"}, ObjectMeta: toObjectMeta("config-gold", "ns")})
+	item := toUnstructured(t, &corev1.ConfigMap{TypeMeta: metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"}, ObjectMeta: toObjectMeta("config-${NAMESPACE_LABEL:tier}", "")})
+	rc := &ReconciliationContext{
+		cfg: &syncv1beta1.SyncConfig{Spec: syncv1beta1.SyncConfigSpec{
+			Prune:     true,
+			SyncItems: []syncv1beta1.SyncItem{{Manifest: syncv1beta1.Manifest{Unstructured: item}}},
+		}},
+		renderedItems: map[string]map[string]bool{},
+	}
+
+	obj, err := rc.renderSyncItem(0, namespaceFromString("ns"))
+
+	assert.ErrorIs(t, err, errRenderFailed)
+	assert.False(t, rc.isRendered(obj))
+	assert.False(t, rc.shouldPrune(&existing, corev1.NamespaceActive, true))
+}
@@ -1,12 +1,34 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
+)
 
-	syncv1alpha1 "github.com/vshn/espejo/api/v1alpha1"
+const (
+	// maxStatusTargets is the maximum number of objects listed in the status of a SyncConfig.
+	maxStatusTargets = 50
+	// maxTargetMessageLength is the maximum length of the message of an object listed in the status of a SyncConfig.
+	maxTargetMessageLength = 256
 )
 
+// targetResultOrder defines the order in which the objects are listed in the status of a SyncConfig.
+var targetResultOrder = []syncv1beta1.TargetResult{
+	syncv1beta1.TargetResultFailed,
+	syncv1beta1.TargetResultSkipped,
+	syncv1beta1.TargetResultDeleted,
+	syncv1beta1.TargetResultSynced,
+}
+
 func (r *SyncConfigReconciler) shouldSkipStatusUpdate() bool {
 	return r.NamespaceScope != ""
 }
@@ -17,9 +39,15 @@ func (r *SyncConfigReconciler) updateStatus(rc *ReconciliationContext) error {
 	}
 	status := rc.cfg.Status
 
+	status.ObservedGeneration = rc.cfg.Generation
 	status.SynchronizedItemCount = rc.syncCount
 	status.DeletedItemCount = rc.deleteCount
 	status.FailedItemCount = rc.failCount
+	status.SkippedItemCount = rc.skipCount
+	status.MatchedNamespaceCount = rc.matchedNamespaceCount
+	status.Targets, status.OmittedTargetCount = rc.statusTargets(status.Targets, metav1.Now())
+	status.ManagedKinds = rc.managedKinds
+	status.DryRun = rc.dryRunStatus()
 
 	rc.cfg.Status = status
 	err := r.Client.Status().Update(rc.ctx, rc.cfg)
@@ -27,63 +55,222 @@ func (r *SyncConfigReconciler) updateStatus(rc *ReconciliationContext) error {
 		r.Log.Error(err, "Could not update SyncConfig.", getLoggingKeysAndValuesForSyncConfig(rc.cfg)...)
 		return err
 	}
-	r.Log.WithValues("syncCount", rc.syncCount, "deleteCount", rc.deleteCount, "failCount", rc.failCount).
+	r.Log.WithValues("syncCount", rc.syncCount, "deleteCount", rc.deleteCount, "failCount", rc.failCount, "skipCount", rc.skipCount).
 		Info("Updated SyncConfig status.", getLoggingKeysAndValuesForSyncConfig(rc.cfg)...)
 	return nil
 }
 
 // SetStatusCondition adds the given condition to the status condition of the SyncConfig. Overwrites existing conditions
-// of the same type.
+// of the same type. The observed generation of the condition is set to the generation of the SyncConfig.
 func (rc *ReconciliationContext) SetStatusCondition(condition metav1.Condition) {
+	condition.ObservedGeneration = rc.cfg.Generation
 	meta.SetStatusCondition(&rc.cfg.Status.Conditions, condition)
 }
 
 // SetStatusIfExisting sets the condition of the given type to the given status, if the condition already exists, otherwise noop
-func (rc *ReconciliationContext) SetStatusIfExisting(conditionType syncv1alpha1.ConditionType, status metav1.ConditionStatus) {
+func (rc *ReconciliationContext) SetStatusIfExisting(conditionType syncv1beta1.ConditionType, status metav1.ConditionStatus) {
 	if condition := meta.FindStatusCondition(rc.cfg.Status.Conditions, conditionType.String()); condition != nil {
 		condition.Status = status
-		meta.SetStatusCondition(&rc.cfg.Status.Conditions, *condition)
+		rc.SetStatusCondition(*condition)
+	}
+}
+
+// SetReconciledConditions sets the Ready, Degraded, Progressing and Errored conditions after a completed reconciliation.
+// The SyncConfig is Ready if no object failed, otherwise it is Degraded with the most frequent reason of the failures.
+func (rc *ReconciliationContext) SetReconciledConditions() {
+	if rc.failCount == 0 {
+		rc.SetStatusCondition(CreateStatusConditionReady(true))
+		rc.SetStatusCondition(CreateStatusConditionDegraded(false, syncv1beta1.SyncReasonSucceeded, "All objects have been synced or deleted"))
+	} else {
+		reason := rc.dominantFailureReason()
+		message := fmt.Sprintf("%d objects could not be synced or deleted", rc.failCount)
+		rc.SetStatusCondition(CreateStatusConditionNotReady(reason, message))
+		rc.SetStatusCondition(CreateStatusConditionDegraded(true, reason, message))
+	}
+	rc.SetStatusCondition(CreateStatusConditionProgressing(false, syncv1beta1.SyncReasonReconciled, "Reconciliation completed"))
+	if rc.isReconcileFailed() {
+		rc.SetStatusCondition(CreateStatusConditionErrored(fmt.Errorf("could not sync or delete any items")))
+	} else {
+		rc.SetStatusIfExisting(syncv1beta1.ConditionErrored, metav1.ConditionFalse)
+	}
+}
+
+// SetInvalidConditions sets the conditions of a SyncConfig with the given validation error.
+func (rc *ReconciliationContext) SetInvalidConditions(err error) {
+	rc.SetStatusCondition(CreateStatusConditionInvalid(err))
+	rc.SetStatusCondition(CreateStatusConditionNotReady(syncv1beta1.SyncReasonInvalid, err.Error()))
+	rc.SetStatusCondition(CreateStatusConditionDegraded(true, syncv1beta1.SyncReasonInvalid, err.Error()))
+	rc.SetStatusCondition(CreateStatusConditionProgressing(false, syncv1beta1.SyncReasonInvalid, "The SyncConfig is not reconciled until its spec is fixed"))
+}
+
+// dominantFailureReason returns the most frequent reason of the failed objects.
+// Ties are resolved alphabetically so that the reason does not change between reconciliations.
+func (rc *ReconciliationContext) dominantFailureReason() string {
+	reason := syncv1beta1.SyncReasonFailed
+	var count int64
+	for r, c := range rc.failureReasons {
+		if c > count || (c == count && r < reason) {
+			reason, count = r, c
+		}
 	}
+	return reason
 }
 
 // CreateStatusConditionReady is a shortcut for adding a ConditionConfigReady condition.
 func CreateStatusConditionReady(isReady bool) metav1.Condition {
 	readyCondition := metav1.Condition{
 		Status:             metav1.ConditionFalse,
-		Type:               syncv1alpha1.ConditionConfigReady.String(),
+		Type:               syncv1beta1.ConditionConfigReady.String(),
 		LastTransitionTime: metav1.Now(),
-		Reason:             syncv1alpha1.SyncReasonFailed,
+		Reason:             syncv1beta1.SyncReasonFailed,
 		Message:            "Synchronization failed",
 	}
 	if isReady {
 		readyCondition.Status = metav1.ConditionTrue
-		readyCondition.Reason = syncv1alpha1.SyncReasonSucceeded
+		readyCondition.Reason = syncv1beta1.SyncReasonSucceeded
 		readyCondition.Message = "Synchronization completed successfully"
 	}
 	return readyCondition
 }
 
+// CreateStatusConditionNotReady is a shortcut for adding a ConditionConfigReady condition with status false and the given reason.
+func CreateStatusConditionNotReady(reason, message string) metav1.Condition {
+	return metav1.Condition{
+		Status:             metav1.ConditionFalse,
+		Type:               syncv1beta1.ConditionConfigReady.String(),
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            message,
+	}
+}
+
 // CreateStatusConditionErrored is a shortcut for adding a ConditionErrored condition with the given error message.
 func CreateStatusConditionErrored(err error) metav1.Condition {
 	return metav1.Condition{
-		Status:  metav1.ConditionTrue,
-		Type:    syncv1alpha1.ConditionErrored.String(),
-		Reason:  syncv1alpha1.SyncReasonFailedWithError,
-		Message: err.Error(),
+		Status:             metav1.ConditionTrue,
+		Type:               syncv1beta1.ConditionErrored.String(),
+		LastTransitionTime: metav1.Now(),
+		Reason:             syncv1beta1.SyncReasonFailedWithError,
+		Message:            err.Error(),
+	}
+}
+
+// CreateStatusConditionDegraded is a shortcut for adding a ConditionDegraded condition.
+func CreateStatusConditionDegraded(isDegraded bool, reason, message string) metav1.Condition {
+	return metav1.Condition{
+		Status:             toConditionStatus(isDegraded),
+		Type:               syncv1beta1.ConditionDegraded.String(),
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            message,
+	}
+}
+
+// CreateStatusConditionProgressing is a shortcut for adding a ConditionProgressing condition.
+func CreateStatusConditionProgressing(isProgressing bool, reason, message string) metav1.Condition {
+	return metav1.Condition{
+		Status:             toConditionStatus(isProgressing),
+		Type:               syncv1beta1.ConditionProgressing.String(),
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            message,
 	}
 }
 
+// failureReason returns the condition reason for the given error of a failed object.
+func failureReason(err error) string {
+	switch {
+	case errors.Is(err, errItemConflict) || apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err):
+		return syncv1beta1.SyncReasonConflict
+	case apierrors.IsForbidden(err) || apierrors.IsUnauthorized(err):
+		return syncv1beta1.SyncReasonForbidden
+	case apierrors.IsInvalid(err) || apierrors.IsBadRequest(err):
+		return syncv1beta1.SyncReasonInvalid
+	case meta.IsNoMatchError(err):
+		return syncv1beta1.SyncReasonNoKindMatch
+	case errors.Is(err, errRenderFailed):
+		return syncv1beta1.SyncReasonRenderFailed
+	default:
+		return syncv1beta1.SyncReasonFailed
+	}
+}
+
+func toConditionStatus(b bool) metav1.ConditionStatus {
+	if b {
+		return metav1.ConditionTrue
+	}
+	return metav1.ConditionFalse
+}
+
 // CreateStatusConditionInvalid is a shortcut for adding a ConditionInvalid condition with the given error message.
 func CreateStatusConditionInvalid(err error) metav1.Condition {
 	return metav1.Condition{
 		Status:             metav1.ConditionTrue,
-		Type:               syncv1alpha1.ConditionInvalid.String(),
+		Type:               syncv1beta1.ConditionInvalid.String(),
 		LastTransitionTime: metav1.Now(),
-		Reason:             syncv1alpha1.SyncReasonConfigInvalid,
+		Reason:             syncv1beta1.SyncReasonConfigInvalid,
 		Message:            err.Error(),
 	}
 }
 
+// CreateStatusConditionDeleting is a shortcut for adding a ConditionConfigReady condition while the synced objects of a
+// deleted SyncConfig are being deleted.
+func CreateStatusConditionDeleting(remaining int64) metav1.Condition {
+	return metav1.Condition{
+		Status:             metav1.ConditionFalse,
+		Type:               syncv1beta1.ConditionConfigReady.String(),
+		LastTransitionTime: metav1.Now(),
+		Reason:             syncv1beta1.SyncReasonDeleting,
+		Message:            fmt.Sprintf("Deleting %d remaining synced objects", remaining),
+	}
+}
+
+// CreateStatusConditionDeletionDryRun is a shortcut for adding a ConditionConfigReady condition while a deleted
+// SyncConfig waits for dry run to be disabled before deleting its synced objects.
+func CreateStatusConditionDeletionDryRun(wouldDelete int64) metav1.Condition {
+	return metav1.Condition{
+		Status:             metav1.ConditionFalse,
+		Type:               syncv1beta1.ConditionConfigReady.String(),
+		LastTransitionTime: metav1.Now(),
+		Reason:             syncv1beta1.SyncReasonDeleting,
+		Message:            fmt.Sprintf("Dry run: %d synced objects would be deleted, disable dry run to delete them and release the SyncConfig", wouldDelete),
+	}
+}
+
+// SetConflictCondition sets the ConditionConflict condition naming the conflicting SyncConfigs, if there are any.
+// Otherwise an existing ConditionConflict condition is resolved.
+func (rc *ReconciliationContext) SetConflictCondition() {
+	if len(rc.conflictingConfigs) > 0 {
+		rc.SetStatusCondition(CreateStatusConditionConflict(rc.conflictingConfigs))
+		return
+	}
+	if meta.FindStatusCondition(rc.cfg.Status.Conditions, syncv1beta1.ConditionConflict.String()) != nil {
+		rc.SetStatusCondition(metav1.Condition{
+			Status:             metav1.ConditionFalse,
+			Type:               syncv1beta1.ConditionConflict.String(),
+			LastTransitionTime: metav1.Now(),
+			Reason:             syncv1beta1.SyncReasonNoOverlappingTargets,
+			Message:            "No other SyncConfig syncs the same objects",
+		})
+	}
+}
+
+// CreateStatusConditionConflict is a shortcut for adding a ConditionConflict condition naming the given SyncConfigs.
+func CreateStatusConditionConflict(conflictingConfigs map[string]bool) metav1.Condition {
+	names := make([]string, 0, len(conflictingConfigs))
+	for name := range conflictingConfigs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return metav1.Condition{
+		Status:             metav1.ConditionTrue,
+		Type:               syncv1beta1.ConditionConflict.String(),
+		LastTransitionTime: metav1.Now(),
+		Reason:             syncv1beta1.SyncReasonOverlappingTargets,
+		Message:            fmt.Sprintf("Objects are also synced by SyncConfig %s", strings.Join(names, ", ")),
+	}
+}
+
 // IncrementSyncCount increments the sync count by 1
 func (rc *ReconciliationContext) IncrementSyncCount() {
 	rc.syncCount++
@@ -94,7 +281,105 @@ func (rc *ReconciliationContext) IncrementDeleteCount() {
 	rc.deleteCount++
 }
 
-// IncrementFailCount increments the fail count by 1
-func (rc *ReconciliationContext) IncrementFailCount() {
+// IncrementFailCount increments the fail count by 1 and records the reason of the given error
+func (rc *ReconciliationContext) IncrementFailCount(err error) {
 	rc.failCount++
+	if rc.failureReasons == nil {
+		rc.failureReasons = map[string]int64{}
+	}
+	rc.failureReasons[failureReason(err)]++
+}
+
+// IncrementSkipCount increments the skip count by 1
+func (rc *ReconciliationContext) IncrementSkipCount() {
+	rc.skipCount++
+}
+
+// AddTarget records the result of the given object, so that it can be listed in the status.
+// Per result, only the first maxStatusTargets objects are recorded, the others are only counted.
+func (rc *ReconciliationContext) AddTarget(obj *unstructured.Unstructured, result syncv1beta1.TargetResult, message string) {
+	rc.targetCount++
+	if rc.targets == nil {
+		rc.targets = map[syncv1beta1.TargetResult][]syncv1beta1.TargetStatus{}
+	}
+	if len(rc.targets[result]) >= maxStatusTargets {
+		return
+	}
+	if len(message) > maxTargetMessageLength {
+		message = message[:maxTargetMessageLength-3] + "..."
+	}
+	rc.targets[result] = append(rc.targets[result], syncv1beta1.TargetStatus{
+		TargetReference: syncv1beta1.TargetReference{
+			Namespace:  obj.GetNamespace(),
+			APIVersion: obj.GetAPIVersion(),
+			Kind:       obj.GetKind(),
+			Name:       obj.GetName(),
+		},
+		Result:  result,
+		Message: message,
+	})
+}
+
+// statusTargets returns the recorded targets to be listed in the status and the number of omitted targets.
+// Failed and skipped targets take precedence over successful ones.
+// Targets whose result did not change keep their LastTransitionTime from the given previous targets.
+func (rc *ReconciliationContext) statusTargets(previous []syncv1beta1.TargetStatus, now metav1.Time) ([]syncv1beta1.TargetStatus, int64) {
+	transitions := make(map[syncv1beta1.TargetReference]syncv1beta1.TargetStatus, len(previous))
+	for _, target := range previous {
+		transitions[target.TargetReference] = target
+	}
+
+	var targets []syncv1beta1.TargetStatus
+	for _, result := range targetResultOrder {
+		for _, target := range rc.targets[result] {
+			if len(targets) >= maxStatusTargets {
+				break
+			}
+			target.LastTransitionTime = now
+			if prev, found := transitions[target.TargetReference]; found && prev.Result == target.Result {
+				target.LastTransitionTime = prev.LastTransitionTime
+			}
+			targets = append(targets, target)
+		}
+	}
+	return targets, rc.targetCount - int64(len(targets))
+}
+
+// dryRunStatus returns the changes counted in dry-run mode or nil if the reconciliation did not run in dry-run mode.
+func (rc *ReconciliationContext) dryRunStatus() *syncv1beta1.DryRunStatus {
+	if !rc.dryRun {
+		return nil
+	}
+	status := &syncv1beta1.DryRunStatus{
+		WouldCreate: rc.changes.created,
+		WouldChange: rc.changes.updated + rc.changes.recreated,
+		WouldDelete: rc.changes.deleted,
+	}
+	names := make([]string, 0, len(rc.changes.namespaces))
+	for name := range rc.changes.namespaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if len(status.Namespaces) >= maxStatusTargets {
+			status.OmittedNamespaceCount++
+			continue
+		}
+		changes := rc.changes.namespaces[name]
+		status.Namespaces = append(status.Namespaces, syncv1beta1.NamespaceDryRunStatus{
+			Namespace:   name,
+			WouldCreate: changes.created,
+			WouldChange: changes.changed,
+			WouldDelete: changes.deleted,
+		})
+	}
+	return status
+}
+
+// AddConflictingConfig records the given SyncConfig as syncing the same objects as this SyncConfig.
+func (rc *ReconciliationContext) AddConflictingConfig(other *syncv1beta1.SyncConfig) {
+	if rc.conflictingConfigs == nil {
+		rc.conflictingConfigs = map[string]bool{}
+	}
+	rc.conflictingConfigs[fmt.Sprintf("%s/%s (priority %d)", other.Namespace, other.Name, other.Spec.Priority)] = true
 }
@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
+)
+
+func Test_ReconciliationContext_StatusTargets(t *testing.T) {
+	now := metav1.NewTime(time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC))
+	earlier := metav1.NewTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	failed := toUnstructured(t, &corev1.ConfigMap{TypeMeta: metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"}, ObjectMeta: toObjectMeta("failed", "ns")})
+	synced := toUnstructured(t, &corev1.ConfigMap{TypeMeta: metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"}, ObjectMeta: toObjectMeta("synced", "ns")})
+	failedRef := syncv1beta1.TargetReference{Namespace: "ns", APIVersion: "v1", Kind: "ConfigMap", Name: "failed"}
+
+	tests := map[string]struct {
+		previous               []syncv1beta1.TargetStatus
+		expectedTransitionTime metav1.Time
+	}{
+		"GivenNoPreviousTarget_ThenSetTransitionTime": {
+			expectedTransitionTime: now,
+		},
+		"GivenPreviousTargetWithSameResult_ThenKeepTransitionTime": {
+			previous:               []syncv1beta1.TargetStatus{{TargetReference: failedRef, Result: syncv1beta1.TargetResultFailed, LastTransitionTime: earlier}},
+			expectedTransitionTime: earlier,
+		},
+		"GivenPreviousTargetWithOtherResult_ThenSetTransitionTime": {
+			previous:               []syncv1beta1.TargetStatus{{TargetReference: failedRef, Result: syncv1beta1.TargetResultSynced, LastTransitionTime: earlier}},
+			expectedTransitionTime: now,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			rc := &ReconciliationContext{}
+			rc.AddTarget(&synced, syncv1beta1.TargetResultSynced, "")
+			rc.AddTarget(&failed, syncv1beta1.TargetResultFailed, "forbidden")
+
+			targets, omitted := rc.statusTargets(tt.previous, now)
+
+			assert.Equal(t, int64(0), omitted)
+			assert.Len(t, targets, 2)
+			assert.Equal(t, failedRef, targets[0].TargetReference)
+			assert.Equal(t, "forbidden", targets[0].Message)
+			assert.Equal(t, tt.expectedTransitionTime, targets[0].LastTransitionTime)
+			assert.Equal(t, syncv1beta1.TargetResultSynced, targets[1].Result)
+		})
+	}
+}
+
+func Test_ReconciliationContext_StatusTargets_GivenTooManyTargets_ThenListFailuresFirst(t *testing.T) {
+	rc := &ReconciliationContext{}
+	for i := 0; i < maxStatusTargets*2; i++ {
+		obj := toUnstructured(t, &corev1.ConfigMap{TypeMeta: metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"}, ObjectMeta: toObjectMeta("synced", fmt.Sprintf("ns-%d", i))})
+		rc.AddTarget(&obj, syncv1beta1.TargetResultSynced, "")
+	}
+	failed := toUnstructured(t, &corev1.ConfigMap{TypeMeta: metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"}, ObjectMeta: toObjectMeta("failed", "ns")})
+	rc.AddTarget(&failed, syncv1beta1.TargetResultFailed, "forbidden")
+
+	targets, omitted := rc.statusTargets(nil, metav1.Now())
+
+	assert.Len(t, targets, maxStatusTargets)
+	assert.Equal(t, int64(maxStatusTargets+1), omitted)
+	assert.Equal(t, syncv1beta1.TargetResultFailed, targets[0].Result)
+}
+
+func Test_FailureReason(t *testing.T) {
+	resource := schema.GroupResource{Resource: "configmaps"}
+	tests := map[string]struct {
+		err            error
+		expectedReason string
+	}{
+		"GivenForbiddenError_ThenForbidden": {
+			err:            apierrors.NewForbidden(resource, "cm", errors.New("no permission")),
+			expectedReason: syncv1beta1.SyncReasonForbidden,
+		},
+		"GivenInvalidError_ThenInvalid": {
+			err:            apierrors.NewInvalid(schema.GroupKind{Kind: "ConfigMap"}, "cm", nil),
+			expectedReason: syncv1beta1.SyncReasonInvalid,
+		},
+		"GivenNoMatchError_ThenNoKindMatch": {
+			err:            &meta.NoKindMatchError{GroupKind: schema.GroupKind{Kind: "Unknown"}},
+			expectedReason: syncv1beta1.SyncReasonNoKindMatch,
+		},
+		"GivenWrappedConflictError_ThenConflict": {
+			err:            fmt.Errorf("field ownership conflict: %w", apierrors.NewConflict(resource, "cm", errors.New("conflict"))),
+			expectedReason: syncv1beta1.SyncReasonConflict,
+		},
+		"GivenConflictPolicyError_ThenConflict": {
+			err:            fmt.Errorf("%w: conflict policy is Fail", errItemConflict),
+			expectedReason: syncv1beta1.SyncReasonConflict,
+		},
+		"GivenOtherError_ThenSynchronizationFailed": {
+			err:            errors.New("connection refused"),
+			expectedReason: syncv1beta1.SyncReasonFailed,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedReason, failureReason(tt.err))
+		})
+	}
+}
+
+func Test_ReconciliationContext_SetReconciledConditions(t *testing.T) {
+	forbidden := apierrors.NewForbidden(schema.GroupResource{Resource: "configmaps"}, "cm", errors.New("no permission"))
+	tests := map[string]struct {
+		syncCount        int64
+		failures         []error
+		expectedReady    metav1.ConditionStatus
+		expectedDegraded metav1.ConditionStatus
+		expectedReason   string
+	}{
+		"GivenNoFailures_ThenReady": {
+			syncCount:        2,
+			expectedReady:    metav1.ConditionTrue,
+			expectedDegraded: metav1.ConditionFalse,
+			expectedReason:   syncv1beta1.SyncReasonSucceeded,
+		},
+		"GivenSomeFailures_ThenDegraded": {
+			syncCount:        2,
+			failures:         []error{forbidden, forbidden, errors.New("timeout")},
+			expectedReady:    metav1.ConditionFalse,
+			expectedDegraded: metav1.ConditionTrue,
+			expectedReason:   syncv1beta1.SyncReasonForbidden,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			rc := &ReconciliationContext{cfg: &syncv1beta1.SyncConfig{ObjectMeta: metav1.ObjectMeta{Generation: 3}}, syncCount: tt.syncCount}
+			for _, err := range tt.failures {
+				rc.IncrementFailCount(err)
+			}
+
+			rc.SetReconciledConditions()
+
+			ready := meta.FindStatusCondition(rc.cfg.Status.Conditions, syncv1beta1.ConditionConfigReady.String())
+			require.NotNil(t, ready)
+			assert.Equal(t, tt.expectedReady, ready.Status)
+			assert.Equal(t, int64(3), ready.ObservedGeneration)
+			degraded := meta.FindStatusCondition(rc.cfg.Status.Conditions, syncv1beta1.ConditionDegraded.String())
+			require.NotNil(t, degraded)
+			assert.Equal(t, tt.expectedDegraded, degraded.Status)
+			assert.Equal(t, tt.expectedReason, degraded.Reason)
+			assert.True(t, meta.IsStatusConditionFalse(rc.cfg.Status.Conditions, syncv1beta1.ConditionProgressing.String()))
+		})
+	}
+}
+
+func Test_ReconciliationContext_DryRunStatus(t *testing.T) {
+	newObj := func(name, namespace string) *unstructured.Unstructured {
+		obj := toUnstructured(t, &corev1.ConfigMap{TypeMeta: metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"}, ObjectMeta: toObjectMeta(name, namespace)})
+		return &obj
+	}
+	r := &SyncConfigReconciler{}
+	rc := &ReconciliationContext{cfg: &syncv1beta1.SyncConfig{}, dryRun: true}
+	r.recordChange(rc, newObj("created", "ns-b"), controllerutil.OperationResultCreated)
+	r.recordChange(rc, newObj("unchanged", "ns-b"), controllerutil.OperationResultNone)
+	r.recordChange(rc, newObj("updated", "ns-a"), controllerutil.OperationResultUpdated)
+	r.recordChange(rc, newObj("recreated", "ns-a"), operationResultRecreated)
+	r.recordChange(rc, newObj("deleted", "ns-a"), operationResultDeleted)
+
+	status := rc.dryRunStatus()
+
+	assert.Equal(t, &syncv1beta1.DryRunStatus{
+		WouldCreate: 1,
+		WouldChange: 2,
+		WouldDelete: 1,
+		Namespaces: []syncv1beta1.NamespaceDryRunStatus{
+			{Namespace: "ns-a", WouldChange: 2, WouldDelete: 1},
+			{Namespace: "ns-b", WouldCreate: 1},
+		},
+	}, status)
+}
+
+func Test_ReconciliationContext_DryRunStatus_GivenNoDryRun_ThenNil(t *testing.T) {
+	rc := &ReconciliationContext{cfg: &syncv1beta1.SyncConfig{}}
+
+	assert.Nil(t, rc.dryRunStatus())
+}
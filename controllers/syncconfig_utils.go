@@ -1,14 +1,18 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
 
+	"github.com/google/cel-go/cel"
+	"github.com/google/go-jsonnet"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
 
-	"github.com/vshn/espejo/api/v1alpha1"
+	"github.com/vshn/espejo/api/v1beta1"
 )
 
 func (rc *ReconciliationContext) validateSpec() error {
@@ -16,8 +20,8 @@ func (rc *ReconciliationContext) validateSpec() error {
 	if hasNoNamespaceSelector(rc.cfg.Spec) {
 		return fmt.Errorf("either .spec.namespaceSelector.matchNames or .spec.namespaceSelector.labelSelector is required")
 	}
-	if len(spec.DeleteItems) == 0 && len(spec.SyncItems) == 0 {
-		return fmt.Errorf("either .spec.deleteItems or .spec.syncItems is required")
+	if len(spec.DeleteItems) == 0 && len(spec.SyncItems) == 0 && !hasTemplate(spec) {
+		return fmt.Errorf("either .spec.deleteItems, .spec.syncItems or .spec.template is required")
 	}
 	for _, pattern := range spec.NamespaceSelector.MatchNames {
 		// Adding ^ and $ even if they exist already should not be a problem, the string would still match with ^^pattern$$
@@ -34,6 +38,11 @@ func (rc *ReconciliationContext) validateSpec() error {
 		}
 		rc.ignoreNamesRegex = append(rc.ignoreNamesRegex, rgx)
 	}
+	ignoreDifferences, err := parseIgnoreDifferences(spec.IgnoreDifferences)
+	if err != nil {
+		return fmt.Errorf(".spec.ignoreDifferences is invalid: %w", err)
+	}
+	rc.ignoreDifferences = ignoreDifferences
 	if rc.cfg.Spec.NamespaceSelector.LabelSelector != nil {
 		labelSelector, err := metav1.LabelSelectorAsSelector(rc.cfg.Spec.NamespaceSelector.LabelSelector)
 		if err != nil {
@@ -41,6 +50,50 @@ func (rc *ReconciliationContext) validateSpec() error {
 		}
 		rc.nsSelector = labelSelector
 	}
+	if spec.TemplateEngine == v1beta1.TemplateEngineGoTemplate {
+		for i, item := range spec.SyncItems {
+			if err := parseItemTemplate(item.Manifest.Unstructured.Object); err != nil {
+				return fmt.Errorf(".spec.syncItems[%d] contains an invalid template: %w", i, err)
+			}
+		}
+	}
+	for i, item := range spec.SyncItems {
+		if _, err := parseIgnoreDifferences(item.Options.IgnoreDifferences); err != nil {
+			return fmt.Errorf(".spec.syncItems[%d].options.ignoreDifferences is invalid: %w", i, err)
+		}
+	}
+	rc.itemSelectors = make([]*itemNamespaceSelector, len(spec.SyncItems))
+	for i, item := range spec.SyncItems {
+		selector, err := parseItemNamespaceSelector(item.NamespaceSelector)
+		if err != nil {
+			return fmt.Errorf(".spec.syncItems[%d].namespaceSelector is invalid: %w", i, err)
+		}
+		rc.itemSelectors[i] = selector
+	}
+	rc.deleteSelectors = make([]*itemNamespaceSelector, len(spec.DeleteItems))
+	for i, item := range spec.DeleteItems {
+		selector, err := parseItemNamespaceSelector(item.NamespaceSelector)
+		if err != nil {
+			return fmt.Errorf(".spec.deleteItems[%d].namespaceSelector is invalid: %w", i, err)
+		}
+		rc.deleteSelectors[i] = selector
+	}
+	rc.conditions = make([]cel.Program, len(spec.SyncItems))
+	for i, item := range spec.SyncItems {
+		if item.When == "" {
+			continue
+		}
+		program, err := compileWhen(item.When)
+		if err != nil {
+			return fmt.Errorf(".spec.syncItems[%d].when is invalid: %w", i, err)
+		}
+		rc.conditions[i] = program
+	}
+	if hasTemplate(spec) {
+		if _, err := jsonnet.SnippetToAST(jsonnetFilename, spec.Template.Jsonnet); err != nil {
+			return fmt.Errorf(".spec.template.jsonnet is invalid: %w", err)
+		}
+	}
 
 	return nil
 }
@@ -68,13 +121,191 @@ NamespaceLoop:
 	return namespaces
 }
 
+// itemNamespaceSelector is the parsed namespace selector of a sync or delete item.
+type itemNamespaceSelector struct {
+	matchNamesRegex  []*regexp.Regexp
+	ignoreNamesRegex []*regexp.Regexp
+	labelSelector    labels.Selector
+}
+
+// parseItemNamespaceSelector parses the given namespace selector of an item. It returns nil if the selector is nil.
+func parseItemNamespaceSelector(selector *v1beta1.NamespaceSelector) (*itemNamespaceSelector, error) {
+	if selector == nil {
+		return nil, nil
+	}
+	parsed := &itemNamespaceSelector{}
+	for _, pattern := range selector.MatchNames {
+		rgx, err := regexp.Compile(fmt.Sprintf("^%s$", pattern))
+		if err != nil {
+			return nil, fmt.Errorf("matchNames pattern invalid: %w", err)
+		}
+		parsed.matchNamesRegex = append(parsed.matchNamesRegex, rgx)
+	}
+	for _, pattern := range selector.IgnoreNames {
+		rgx, err := regexp.Compile(fmt.Sprintf("^%s$", pattern))
+		if err != nil {
+			return nil, fmt.Errorf("ignoreNames pattern invalid: %w", err)
+		}
+		parsed.ignoreNamesRegex = append(parsed.ignoreNamesRegex, rgx)
+	}
+	if selector.LabelSelector != nil {
+		labelSelector, err := metav1.LabelSelectorAsSelector(selector.LabelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("labelSelector is invalid: %w", err)
+		}
+		parsed.labelSelector = labelSelector
+	}
+	return parsed, nil
+}
+
+// matches returns true if the given namespace is selected. A nil selector selects all namespaces.
+// Like the namespace selector of the SyncConfig, a namespace is selected if it matches the label selector or one of the
+// name patterns, unless it is ignored. Without label selector and name patterns, all namespaces that are not ignored
+// are selected.
+func (s *itemNamespaceSelector) matches(ns v1.Namespace) bool {
+	if s == nil {
+		return true
+	}
+	for _, regex := range s.ignoreNamesRegex {
+		if regex.MatchString(ns.Name) {
+			return false
+		}
+	}
+	if s.labelSelector == nil && len(s.matchNamesRegex) == 0 {
+		return true
+	}
+	if s.labelSelector != nil && s.labelSelector.Matches(labels.Set(ns.GetLabels())) {
+		return true
+	}
+	for _, regex := range s.matchNamesRegex {
+		if regex.MatchString(ns.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSelected returns whether the sync item with the given index is synced into the given target namespace according
+// to its namespace selector and its when expression.
+func (rc *ReconciliationContext) isSelected(index int, targetNamespace v1.Namespace) (bool, error) {
+	if index < len(rc.itemSelectors) && !rc.itemSelectors[index].matches(targetNamespace) {
+		return false, nil
+	}
+	return rc.evaluateWhen(index, targetNamespace)
+}
+
+// isDeleteSelected returns whether the delete item with the given index is deleted from the given target namespace
+// according to its namespace selector.
+func (rc *ReconciliationContext) isDeleteSelected(index int, targetNamespace v1.Namespace) bool {
+	return index >= len(rc.deleteSelectors) || rc.deleteSelectors[index].matches(targetNamespace)
+}
+
+// renderItems renders the sync items selected for the given target namespace and the template of the SyncConfig.
+// Items that cannot be rendered are left out and their errors are returned joined.
+func (rc *ReconciliationContext) renderItems(targetNamespace v1.Namespace) ([]*unstructured.Unstructured, error) {
+	items := make([]*unstructured.Unstructured, 0, len(rc.cfg.Spec.SyncItems))
+	var errs []error
+	for i, item := range rc.cfg.Spec.SyncItems {
+		selected, err := rc.isSelected(i, targetNamespace)
+		if err == nil && !selected {
+			continue
+		}
+		obj, renderErr := rc.renderItem(item, targetNamespace)
+		if err == nil {
+			err = renderErr
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", obj.GetKind(), obj.GetName(), err))
+			continue
+		}
+		items = append(items, obj)
+	}
+	objs, err := rc.renderTemplate(targetNamespace)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("template: %w", err))
+	}
+	return append(items, objs...), errors.Join(errs...)
+}
+
+// renderSyncItem renders the sync item with the given index for the given target namespace and marks the result as
+// rendered for pruning. It returns nil if the item is not selected for the namespace. If the item cannot be rendered,
+// the name of the object it renders is unknown, so no objects are pruned from the namespace.
+func (rc *ReconciliationContext) renderSyncItem(index int, targetNamespace v1.Namespace) (*unstructured.Unstructured, error) {
+	item := rc.cfg.Spec.SyncItems[index]
+	selected, err := rc.isSelected(index, targetNamespace)
+	if err == nil && !selected {
+		return nil, nil
+	}
+	obj, renderErr := rc.renderItem(item, targetNamespace)
+	if err == nil {
+		err = renderErr
+	}
+	if err != nil {
+		rc.markRenderFailed(targetNamespace.Name)
+		return obj, err
+	}
+	rc.markRendered(obj)
+	return obj, nil
+}
+
+// renderItem renders the given sync item for the given target namespace.
+// If the item cannot be rendered, the object is returned with the target namespace set, along with an error.
+func (rc *ReconciliationContext) renderItem(item v1beta1.SyncItem, targetNamespace v1.Namespace) (*unstructured.Unstructured, error) {
+	obj := item.Manifest.Unstructured.DeepCopy()
+	obj.SetNamespace(targetNamespace.Name)
+	values := placeholderValues{namespace: targetNamespace, cfg: rc.cfg, clusterName: rc.clusterName}
+	if rc.cfg.Spec.TemplateEngine != v1beta1.TemplateEngineGoTemplate {
+		if err := renderStrings(obj.Object, values.replace); err != nil {
+			return obj, fmt.Errorf("%w: %w", errRenderFailed, err)
+		}
+		return obj, nil
+	}
+
+	rendered, err := newTemplateData(values).render(obj.Object)
+	if err != nil {
+		return obj, fmt.Errorf("%w: %w", errRenderFailed, err)
+	}
+	result := &unstructured.Unstructured{Object: rendered}
+	if result.GetAPIVersion() == "" || result.GetKind() == "" || result.GetName() == "" {
+		return obj, fmt.Errorf("%w: rendered item requires apiVersion, kind and metadata.name", errRenderFailed)
+	}
+	result.SetNamespace(targetNamespace.Name)
+	return result, nil
+}
+
+// forceRecreate returns whether objects synced with the given options should be recreated if an update fails.
+func (rc *ReconciliationContext) forceRecreate(options v1beta1.SyncItemOptions) bool {
+	if options.ForceRecreate != nil {
+		return *options.ForceRecreate
+	}
+	return rc.cfg.Spec.ForceRecreate
+}
+
+// ignoredFields returns the ignored fields that apply to objects synced with the given options.
+// The options must have been validated with validateSpec.
+func (rc *ReconciliationContext) ignoredFields(options v1beta1.SyncItemOptions) []ignoreDifference {
+	if len(options.IgnoreDifferences) == 0 {
+		return rc.ignoreDifferences
+	}
+	itemDiffs, _ := parseIgnoreDifferences(options.IgnoreDifferences)
+	return append(append([]ignoreDifference{}, rc.ignoreDifferences...), itemDiffs...)
+}
+
+// conflictPolicy returns the conflict policy that applies to objects synced with the given options.
+func (rc *ReconciliationContext) conflictPolicy(options v1beta1.SyncItemOptions) v1beta1.ConflictPolicy {
+	if options.ConflictPolicy != "" {
+		return options.ConflictPolicy
+	}
+	return rc.cfg.Spec.ConflictPolicy
+}
+
 // isReconcileFailed returns true if no objects could be synced or deleted and failedCount is > 0
 func (rc *ReconciliationContext) isReconcileFailed() bool {
 	return rc.syncCount == 0 && rc.deleteCount == 0 && rc.failCount > 0
 }
 
 // hasNoNamespaceSelector will return true if the SyncConfigSpec does not have a valid namespace selector
-func hasNoNamespaceSelector(spec v1alpha1.SyncConfigSpec) bool {
+func hasNoNamespaceSelector(spec v1beta1.SyncConfigSpec) bool {
 	if spec.NamespaceSelector == nil {
 		return true
 	}
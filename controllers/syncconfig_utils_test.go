@@ -12,7 +12,7 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 
-	syncv1alpha1 "github.com/vshn/espejo/api/v1alpha1"
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
 )
 
 func Test_ReconciliationContext_FilterNamespaces(t *testing.T) {
@@ -64,40 +64,127 @@ func Test_ReconciliationContext_validateSpec(t *testing.T) {
 		expectErr          bool
 		containsErrMessage string
 
-		cfg              *syncv1alpha1.SyncConfig
+		cfg              *syncv1beta1.SyncConfig
 		matchNamesRegex  []*regexp.Regexp
 		ignoreNamesRegex []*regexp.Regexp
 		nsSelector       labels.Selector
 	}{
 		"GivenSpecWithInvalidMatchNamesSelector_WhenParsingRegex_ThenReturnRegexError": {
-			cfg: &syncv1alpha1.SyncConfig{
-				Spec: syncv1alpha1.SyncConfigSpec{
-					NamespaceSelector: &syncv1alpha1.NamespaceSelector{
+			cfg: &syncv1beta1.SyncConfig{
+				Spec: syncv1beta1.SyncConfigSpec{
+					NamespaceSelector: &syncv1beta1.NamespaceSelector{
 						MatchNames: []string{"["},
 					},
-					SyncItems: []syncv1alpha1.Manifest{{Unstructured: toUnstructured(t, &corev1.ConfigMap{})}},
+					SyncItems: []syncv1beta1.SyncItem{{Manifest: syncv1beta1.Manifest{Unstructured: toUnstructured(t, &corev1.ConfigMap{})}}},
 				},
 			},
 			containsErrMessage: "error parsing regexp",
 			expectErr:          true,
 		},
 		"GivenSpecWithInvalidIgnoreNamesSelector_WhenParsingRegex_ThenReturnRegexError": {
-			cfg: &syncv1alpha1.SyncConfig{
-				Spec: syncv1alpha1.SyncConfigSpec{
-					NamespaceSelector: &syncv1alpha1.NamespaceSelector{
+			cfg: &syncv1beta1.SyncConfig{
+				Spec: syncv1beta1.SyncConfigSpec{
+					NamespaceSelector: &syncv1beta1.NamespaceSelector{
 						IgnoreNames: []string{"["},
 						MatchNames:  []string{".*"},
 					},
-					SyncItems: []syncv1alpha1.Manifest{{Unstructured: toUnstructured(t, &corev1.ConfigMap{})}},
+					SyncItems: []syncv1beta1.SyncItem{{Manifest: syncv1beta1.Manifest{Unstructured: toUnstructured(t, &corev1.ConfigMap{})}}},
 				},
 			},
 			containsErrMessage: "error parsing regexp",
 			expectErr:          true,
 		},
+		"GivenSpecWithInvalidIgnoreDifference_WhenValidating_ThenReturnPathError": {
+			cfg: &syncv1beta1.SyncConfig{
+				Spec: syncv1beta1.SyncConfigSpec{
+					NamespaceSelector: &syncv1beta1.NamespaceSelector{
+						MatchNames: []string{".*"},
+					},
+					SyncItems:         []syncv1beta1.SyncItem{{Manifest: syncv1beta1.Manifest{Unstructured: toUnstructured(t, &corev1.ConfigMap{})}}},
+					IgnoreDifferences: []syncv1beta1.IgnoreDifference{{JSONPointers: []string{"spec/replicas"}}},
+				},
+			},
+			containsErrMessage: "must start with '/'",
+			expectErr:          true,
+		},
+		"GivenItemWithInvalidIgnoreDifference_WhenValidating_ThenReturnPathError": {
+			cfg: &syncv1beta1.SyncConfig{
+				Spec: syncv1beta1.SyncConfigSpec{
+					NamespaceSelector: &syncv1beta1.NamespaceSelector{
+						MatchNames: []string{".*"},
+					},
+					SyncItems: []syncv1beta1.SyncItem{{
+						Manifest: syncv1beta1.Manifest{Unstructured: toUnstructured(t, &corev1.ConfigMap{})},
+						Options:  syncv1beta1.SyncItemOptions{IgnoreDifferences: []syncv1beta1.IgnoreDifference{{JSONPaths: []string{"spec"}}}},
+					}},
+				},
+			},
+			containsErrMessage: ".spec.syncItems[0].options.ignoreDifferences is invalid",
+			expectErr:          true,
+		},
+		"GivenSpecWithInvalidGoTemplate_WhenValidating_ThenReturnTemplateError": {
+			cfg: &syncv1beta1.SyncConfig{
+				Spec: syncv1beta1.SyncConfigSpec{
+					NamespaceSelector: &syncv1beta1.NamespaceSelector{
+						MatchNames: []string{".*"},
+					},
+					SyncItems: []syncv1beta1.SyncItem{{Manifest: syncv1beta1.Manifest{Unstructured: toUnstructured(t, &corev1.ConfigMap{
+						Data: map[string]string{"key": "{{ .Namespace.Name "},
+					})}}},
+					TemplateEngine: syncv1beta1.TemplateEngineGoTemplate,
+				},
+			},
+			containsErrMessage: ".spec.syncItems[0] contains an invalid template",
+			expectErr:          true,
+		},
+		"GivenSpecWithInvalidJsonnet_WhenValidating_ThenReturnTemplateError": {
+			cfg: &syncv1beta1.SyncConfig{
+				Spec: syncv1beta1.SyncConfigSpec{
+					NamespaceSelector: &syncv1beta1.NamespaceSelector{
+						MatchNames: []string{".*"},
+					},
+					Template: &syncv1beta1.Template{Jsonnet: `[{ kind: "ConfigMap" `},
+				},
+			},
+			containsErrMessage: ".spec.template.jsonnet is invalid",
+			expectErr:          true,
+		},
+		"GivenSpecWithInvalidWhen_WhenValidating_ThenReturnWhenError": {
+			cfg: &syncv1beta1.SyncConfig{
+				Spec: syncv1beta1.SyncConfigSpec{
+					NamespaceSelector: &syncv1beta1.NamespaceSelector{
+						MatchNames: []string{".*"},
+					},
+					SyncItems: []syncv1beta1.SyncItem{{
+						Manifest: syncv1beta1.Manifest{Unstructured: toUnstructured(t, &corev1.ConfigMap{})},
+						When:     "namespaceObject.metadata.labels['tier'] ==",
+					}},
+				},
+			},
+			containsErrMessage: ".spec.syncItems[0].when is invalid",
+			expectErr:          true,
+		},
+		"GivenDeleteItemWithInvalidNamespaceSelector_WhenValidating_ThenReturnSelectorError": {
+			cfg: &syncv1beta1.SyncConfig{
+				Spec: syncv1beta1.SyncConfigSpec{
+					NamespaceSelector: &syncv1beta1.NamespaceSelector{
+						MatchNames: []string{".*"},
+					},
+					DeleteItems: []syncv1beta1.DeleteMeta{{
+						APIVersion:        "v1",
+						Kind:              "ConfigMap",
+						Name:              "config",
+						NamespaceSelector: &syncv1beta1.NamespaceSelector{IgnoreNames: []string{"["}},
+					}},
+				},
+			},
+			containsErrMessage: ".spec.deleteItems[0].namespaceSelector is invalid: ignoreNames pattern invalid",
+			expectErr:          true,
+		},
 		"GivenSpecWithNoNamespaceSelector_WhenValidating_ThenReturnSelectorError": {
-			cfg: &syncv1alpha1.SyncConfig{
-				Spec: syncv1alpha1.SyncConfigSpec{
-					NamespaceSelector: &syncv1alpha1.NamespaceSelector{
+			cfg: &syncv1beta1.SyncConfig{
+				Spec: syncv1beta1.SyncConfigSpec{
+					NamespaceSelector: &syncv1beta1.NamespaceSelector{
 						MatchNames: []string{},
 					},
 				},
@@ -122,6 +209,95 @@ func Test_ReconciliationContext_validateSpec(t *testing.T) {
 	}
 }
 
+func Test_ReconciliationContext_ItemNamespaceSelectors(t *testing.T) {
+	restricted := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "project-a", Labels: map[string]string{"egress": "restricted"}}}
+	open := namespaceFromString("project-b")
+	system := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "project-system", Labels: map[string]string{"egress": "restricted"}}}
+
+	tests := map[string]struct {
+		givenSelector *syncv1beta1.NamespaceSelector
+		expected      map[string]bool
+	}{
+		"GivenNoSelector_ThenSelectAll": {
+			expected: map[string]bool{"project-a": true, "project-b": true, "project-system": true},
+		},
+		"GivenLabelSelector_ThenSelectLabelled": {
+			givenSelector: &syncv1beta1.NamespaceSelector{LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"egress": "restricted"}}},
+			expected:      map[string]bool{"project-a": true, "project-b": false, "project-system": true},
+		},
+		"GivenLabelSelectorAndMatchNames_ThenSelectEither": {
+			givenSelector: &syncv1beta1.NamespaceSelector{
+				LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"egress": "restricted"}},
+				MatchNames:    []string{"project-b"},
+			},
+			expected: map[string]bool{"project-a": true, "project-b": true, "project-system": true},
+		},
+		"GivenIgnoreNamesOnly_ThenSelectAllButIgnored": {
+			givenSelector: &syncv1beta1.NamespaceSelector{IgnoreNames: []string{".*-system"}},
+			expected:      map[string]bool{"project-a": true, "project-b": true, "project-system": false},
+		},
+		"GivenLabelSelectorAndIgnoreNames_ThenIgnoreTakesPrecedence": {
+			givenSelector: &syncv1beta1.NamespaceSelector{
+				LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"egress": "restricted"}},
+				IgnoreNames:   []string{".*-system"},
+			},
+			expected: map[string]bool{"project-a": true, "project-b": false, "project-system": false},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			rc := &ReconciliationContext{cfg: &syncv1beta1.SyncConfig{Spec: syncv1beta1.SyncConfigSpec{
+				NamespaceSelector: &syncv1beta1.NamespaceSelector{MatchNames: []string{"project-.*"}},
+				SyncItems:         []syncv1beta1.SyncItem{{NamespaceSelector: tt.givenSelector}},
+				DeleteItems:       []syncv1beta1.DeleteMeta{{NamespaceSelector: tt.givenSelector}},
+			}}}
+			require.NoError(t, rc.validateSpec())
+
+			for _, ns := range []corev1.Namespace{restricted, open, system} {
+				selected, err := rc.isSelected(0, ns)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected[ns.Name], selected, "sync item in namespace %s", ns.Name)
+				assert.Equal(t, tt.expected[ns.Name], rc.isDeleteSelected(0, ns), "delete item in namespace %s", ns.Name)
+			}
+		})
+	}
+}
+
+func Test_ReconciliationContext_ItemOptions(t *testing.T) {
+	enabled, disabled := true, false
+	tests := map[string]struct {
+		givenOptions          syncv1beta1.SyncItemOptions
+		expectedForceRecreate bool
+		expectedPolicy        syncv1beta1.ConflictPolicy
+	}{
+		"GivenNoOptions_ThenUseSpec": {
+			expectedForceRecreate: true,
+			expectedPolicy:        syncv1beta1.ConflictPolicyFail,
+		},
+		"GivenOptions_ThenOverrideSpec": {
+			givenOptions:          syncv1beta1.SyncItemOptions{ForceRecreate: &disabled, ConflictPolicy: syncv1beta1.ConflictPolicySkip},
+			expectedForceRecreate: false,
+			expectedPolicy:        syncv1beta1.ConflictPolicySkip,
+		},
+		"GivenForceRecreateOption_ThenOverrideForceRecreateOnly": {
+			givenOptions:          syncv1beta1.SyncItemOptions{ForceRecreate: &enabled},
+			expectedForceRecreate: true,
+			expectedPolicy:        syncv1beta1.ConflictPolicyFail,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			rc := &ReconciliationContext{cfg: &syncv1beta1.SyncConfig{Spec: syncv1beta1.SyncConfigSpec{
+				ForceRecreate:  true,
+				ConflictPolicy: syncv1beta1.ConflictPolicyFail,
+			}}}
+
+			assert.Equal(t, tt.expectedForceRecreate, rc.forceRecreate(tt.givenOptions))
+			assert.Equal(t, tt.expectedPolicy, rc.conflictPolicy(tt.givenOptions))
+		})
+	}
+}
+
 func toRegex(t *testing.T, pattern string) *regexp.Regexp {
 	rgx, err := regexp.Compile(pattern)
 	require.NoError(t, err)
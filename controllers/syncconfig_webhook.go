@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
+)
+
+// +kubebuilder:webhook:path=/validate-sync-appuio-ch-v1beta1-syncconfig,mutating=false,failurePolicy=fail,sideEffects=None,groups=sync.appuio.ch,resources=syncconfigs,verbs=create;update,versions=v1beta1,name=vsyncconfig.sync.appuio.ch,admissionReviewVersions=v1
+
+// SyncConfigValidator validates SyncConfigs at admission.
+type SyncConfigValidator struct {
+	// RESTMapper is used to verify that the kinds of sync and delete items exist and are namespaced.
+	RESTMapper meta.RESTMapper
+	// Scheme is used to look up deprecated API versions of sync and delete items.
+	Scheme *runtime.Scheme
+}
+
+type (
+	// deprecatedAPI is implemented by the types of prerelease API versions that are deprecated.
+	deprecatedAPI interface {
+		APILifecycleDeprecated() (major, minor int)
+	}
+	// removedAPI is implemented by the types of prerelease API versions that are removed.
+	removedAPI interface {
+		APILifecycleRemoved() (major, minor int)
+	}
+	// replacedAPI is implemented by the types of prerelease API versions that have a replacement.
+	replacedAPI interface {
+		APILifecycleReplacement() schema.GroupVersionKind
+	}
+)
+
+// SetupWebhookWithManager registers the validating webhook for SyncConfigs with the given manager.
+func (v *SyncConfigValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&syncv1beta1.SyncConfig{}).
+		WithValidator(v).
+		Complete()
+}
+
+// ValidateCreate validates a new SyncConfig.
+func (v *SyncConfigValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return v.validate(obj)
+}
+
+// ValidateUpdate validates a changed SyncConfig.
+// SyncConfigs that are being deleted are not validated, so that their finalizer can always be removed.
+func (v *SyncConfigValidator) ValidateUpdate(_ context.Context, _, newObj runtime.Object) (admission.Warnings, error) {
+	if cfg, ok := newObj.(*syncv1beta1.SyncConfig); ok && cfg.DeletionTimestamp != nil {
+		return nil, nil
+	}
+	return v.validate(newObj)
+}
+
+// ValidateDelete allows all deletions.
+func (v *SyncConfigValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate runs the same checks as a reconciliation and verifies that the kinds of all sync and delete items exist
+// and are namespaced. Deprecated API versions and kinds that could not be verified are returned as warnings.
+func (v *SyncConfigValidator) validate(obj runtime.Object) (admission.Warnings, error) {
+	cfg, ok := obj.(*syncv1beta1.SyncConfig)
+	if !ok {
+		return nil, fmt.Errorf("expected a SyncConfig but got %T", obj)
+	}
+
+	var errs field.ErrorList
+	var warnings admission.Warnings
+	rc := &ReconciliationContext{cfg: cfg}
+	if err := rc.validateSpec(); err != nil {
+		errs = append(errs, field.Invalid(field.NewPath("spec"), field.OmitValueType{}, err.Error()))
+	}
+	for i, item := range cfg.Spec.SyncItems {
+		path := field.NewPath("spec", "syncItems").Index(i)
+		warning, err := v.validateKind(path, item.Manifest.GroupVersionKind())
+		errs, warnings = appendResult(errs, warnings, warning, err)
+	}
+	for i, item := range cfg.Spec.DeleteItems {
+		path := field.NewPath("spec", "deleteItems").Index(i)
+		warning, err := v.validateKind(path, schema.FromAPIVersionAndKind(item.APIVersion, item.Kind))
+		errs, warnings = appendResult(errs, warnings, warning, err)
+	}
+
+	if len(errs) > 0 {
+		return warnings, apierrors.NewInvalid(syncv1beta1.GroupVersion.WithKind("SyncConfig").GroupKind(), cfg.Name, errs)
+	}
+	return warnings, nil
+}
+
+// validateKind verifies that the given kind exists and is namespaced.
+// It returns a warning if the kind is deprecated or could not be verified.
+func (v *SyncConfigValidator) validateKind(path *field.Path, gvk schema.GroupVersionKind) (string, *field.Error) {
+	if gvk.Version == "" || gvk.Kind == "" {
+		return "", field.Required(path, "apiVersion and kind are required")
+	}
+	mapping, err := v.RESTMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		return "", field.Invalid(path.Child("kind"), gvk.Kind, fmt.Sprintf("kind does not exist in apiVersion %s", gvk.GroupVersion()))
+	}
+	if err != nil {
+		return fmt.Sprintf("%s: could not verify kind %s: %v", path, gvk.Kind, err), nil
+	}
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return "", field.Invalid(path.Child("kind"), gvk.Kind, "kind is cluster-scoped, only namespaced kinds can be synced")
+	}
+	if deprecation := v.deprecationWarning(gvk); deprecation != "" {
+		return fmt.Sprintf("%s: %s", path, deprecation), nil
+	}
+	return "", nil
+}
+
+// deprecationWarning returns a warning in the format of the API server if the given kind is deprecated.
+func (v *SyncConfigValidator) deprecationWarning(gvk schema.GroupVersionKind) string {
+	if v.Scheme == nil || !v.Scheme.Recognizes(gvk) {
+		return ""
+	}
+	obj, err := v.Scheme.New(gvk)
+	if err != nil {
+		return ""
+	}
+	deprecated, ok := obj.(deprecatedAPI)
+	if !ok {
+		return ""
+	}
+	major, minor := deprecated.APILifecycleDeprecated()
+	if major == 0 && minor == 0 {
+		return ""
+	}
+	warning := fmt.Sprintf("%s %s is deprecated in v%d.%d+", gvk.GroupVersion(), gvk.Kind, major, minor)
+	if removed, ok := obj.(removedAPI); ok {
+		if major, minor := removed.APILifecycleRemoved(); major != 0 || minor != 0 {
+			warning += fmt.Sprintf(", unavailable in v%d.%d+", major, minor)
+		}
+	}
+	if replaced, ok := obj.(replacedAPI); ok {
+		if replacement := replaced.APILifecycleReplacement(); !replacement.Empty() {
+			warning += fmt.Sprintf("; use %s %s", replacement.GroupVersion(), replacement.Kind)
+		}
+	}
+	return warning
+}
+
+// appendResult appends the given warning and error to the given lists, if they are set.
+func appendResult(errs field.ErrorList, warnings admission.Warnings, warning string, err *field.Error) (field.ErrorList, admission.Warnings) {
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if warning != "" {
+		warnings = append(warnings, warning)
+	}
+	return errs, warnings
+}
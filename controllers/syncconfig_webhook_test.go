@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
+)
+
+func Test_SyncConfigValidator_ValidateCreate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, meta.RESTScopeRoot)
+	mapper.Add(schema.GroupVersionKind{Group: "extensions", Version: "v1beta1", Kind: "NetworkPolicy"}, meta.RESTScopeNamespace)
+	validator := &SyncConfigValidator{RESTMapper: mapper, Scheme: scheme}
+	item := func(apiVersion, kind string) syncv1beta1.SyncItem {
+		obj := unstructured.Unstructured{}
+		obj.SetAPIVersion(apiVersion)
+		obj.SetKind(kind)
+		obj.SetName("item")
+		return syncv1beta1.SyncItem{Manifest: syncv1beta1.Manifest{Unstructured: obj}}
+	}
+
+	tests := map[string]struct {
+		givenMatchNames  []string
+		givenSyncItems   []syncv1beta1.SyncItem
+		givenDeleteItems []syncv1beta1.DeleteMeta
+		expectedErr      string
+		expectedWarnings admission.Warnings
+	}{
+		"GivenValidSyncConfig_ThenAllow": {
+			givenSyncItems:   []syncv1beta1.SyncItem{item("v1", "ConfigMap")},
+			givenDeleteItems: []syncv1beta1.DeleteMeta{{APIVersion: "v1", Kind: "ConfigMap", Name: "old"}},
+		},
+		"GivenInvalidPattern_ThenDeny": {
+			givenMatchNames: []string{"("},
+			givenSyncItems:  []syncv1beta1.SyncItem{item("v1", "ConfigMap")},
+			expectedErr:     ".spec.namespaceSelector.matchNames pattern invalid",
+		},
+		"GivenUnknownKind_ThenDeny": {
+			givenSyncItems: []syncv1beta1.SyncItem{item("v1", "ConfigMap"), item("example.com/v1", "Unknown")},
+			expectedErr:    "spec.syncItems[1].kind: Invalid value: \"Unknown\": kind does not exist in apiVersion example.com/v1",
+		},
+		"GivenClusterScopedKind_ThenDeny": {
+			givenDeleteItems: []syncv1beta1.DeleteMeta{{APIVersion: "v1", Kind: "Namespace", Name: "old"}},
+			expectedErr:      "spec.deleteItems[0].kind: Invalid value: \"Namespace\": kind is cluster-scoped",
+		},
+		"GivenDeprecatedVersion_ThenAllowWithWarning": {
+			givenSyncItems: []syncv1beta1.SyncItem{item("extensions/v1beta1", "NetworkPolicy")},
+			expectedWarnings: admission.Warnings{
+				"spec.syncItems[0]: extensions/v1beta1 NetworkPolicy is deprecated in v1.9+, unavailable in v1.16+; use networking.k8s.io/v1 NetworkPolicy",
+			},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			matchNames := tt.givenMatchNames
+			if matchNames == nil {
+				matchNames = []string{"default"}
+			}
+			cfg := &syncv1beta1.SyncConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "config"},
+				Spec: syncv1beta1.SyncConfigSpec{
+					NamespaceSelector: &syncv1beta1.NamespaceSelector{MatchNames: matchNames},
+					SyncItems:         tt.givenSyncItems,
+					DeleteItems:       tt.givenDeleteItems,
+				},
+			}
+
+			warnings, err := validator.ValidateCreate(context.Background(), cfg)
+
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedWarnings, warnings)
+		})
+	}
+}
+
+func Test_SyncConfigValidator_ValidateUpdate_GivenDeletedSyncConfig_ThenAllow(t *testing.T) {
+	now := metav1.Now()
+	cfg := &syncv1beta1.SyncConfig{ObjectMeta: metav1.ObjectMeta{Name: "config", DeletionTimestamp: &now}}
+
+	warnings, err := (&SyncConfigValidator{}).ValidateUpdate(context.Background(), cfg, cfg)
+
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+}
@@ -0,0 +1,335 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
+)
+
+const (
+	// driftBackoffBase and driftBackoffMax are the initial and the maximum delay of repeated drift corrections of the
+	// same object.
+	driftBackoffBase = time.Second
+	driftBackoffMax  = 5 * time.Minute
+)
+
+// ItemWatcher dynamically watches the kinds of synced objects.
+// When a field of a synced object that espejo manages is modified, or the object is deleted, the SyncConfig that synced
+// the object is enqueued in the NamespaceReconciler for the namespace of the object, which corrects the drift.
+type ItemWatcher struct {
+	Log logr.Logger
+	// Controller is the controller that receives the namespace requests, usually the one of the NamespaceReconciler.
+	Controller controller.Controller
+
+	cache   cache.Cache
+	mu      sync.Mutex
+	watched map[schema.GroupVersionKind]bool
+
+	manifestsMu sync.RWMutex
+	// manifests holds the manifest each object has last been synced with by target key, without the ignored fields.
+	manifests map[string]*unstructured.Unstructured
+	backoff   driftBackoff
+}
+
+// driftBackoff delays repeated drift corrections of the same object exponentially, so that espejo does not fight with
+// another controller over a field in a tight loop. The delay is reset once the object has not drifted for driftBackoffMax.
+type driftBackoff struct {
+	mu      sync.Mutex
+	entries map[string]*driftBackoffEntry
+	swept   time.Time
+}
+
+type driftBackoffEntry struct {
+	delay time.Duration
+	last  time.Time
+}
+
+// NewItemWatcher creates a new ItemWatcher with a dedicated cache that only contains objects synced by espejo in all namespaces.
+// The cache is started by the given manager.
+func NewItemWatcher(mgr ctrl.Manager, log logr.Logger) (*ItemWatcher, error) {
+	c, err := cache.New(mgr.GetConfig(), cache.Options{
+		Scheme: mgr.GetScheme(),
+		Mapper: mgr.GetRESTMapper(),
+		DefaultLabelSelector: labels.SelectorFromSet(labels.Set{
+			syncv1beta1.LabelManagedBy: syncv1beta1.ManagedByEspejo,
+		}),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := mgr.Add(c); err != nil {
+		return nil, err
+	}
+	return &ItemWatcher{
+		Log:     log,
+		cache:   c,
+		watched: map[schema.GroupVersionKind]bool{},
+	}, nil
+}
+
+// Watch starts watching objects of the given kind, unless they are watched already.
+func (w *ItemWatcher) Watch(kind syncv1beta1.ManagedKind) error {
+	gvk := schema.FromAPIVersionAndKind(kind.APIVersion, kind.Kind)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.watched[gvk] {
+		return nil
+	}
+	if w.Controller == nil {
+		return fmt.Errorf("cannot watch %s: no controller configured", gvk)
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	err := w.Controller.Watch(source.Kind[client.Object](w.cache, obj, w.driftHandler(), w.driftPredicate()))
+	if err != nil {
+		return err
+	}
+	w.Log.Info("Watching synced objects", "APIVersion", kind.APIVersion, "Kind", kind.Kind)
+	w.watched[gvk] = true
+	return nil
+}
+
+// mapToSyncConfigRequest maps the given object to a request that reconciles the SyncConfig that synced the object for
+// the namespace of the object only. Objects without the owner annotations are mapped to a request of their namespace.
+func mapToSyncConfigRequest(ctx context.Context, obj client.Object) []ctrl.Request {
+	if obj.GetNamespace() == "" {
+		return nil
+	}
+	annotations := obj.GetAnnotations()
+	owner := types.NamespacedName{
+		Namespace: annotations[syncv1beta1.AnnotationOwnerNamespace],
+		Name:      annotations[syncv1beta1.AnnotationOwnerName],
+	}
+	if owner.Namespace == "" || owner.Name == "" {
+		return mapToNamespaceRequest(ctx, obj)
+	}
+	return []ctrl.Request{newScopedRequest(owner, obj.GetNamespace())}
+}
+
+// mapToNamespaceRequest maps the given object to a reconcile request of its namespace.
+func mapToNamespaceRequest(_ context.Context, obj client.Object) []ctrl.Request {
+	if obj.GetNamespace() == "" {
+		return nil
+	}
+	return []ctrl.Request{{NamespacedName: types.NamespacedName{Name: obj.GetNamespace()}}}
+}
+
+// recordManifest records the given object as the manifest its synced object has been synced with.
+// The ignored fields must have been removed from the object, changes to them are not drift.
+func (w *ItemWatcher) recordManifest(obj *unstructured.Unstructured) {
+	w.manifestsMu.Lock()
+	defer w.manifestsMu.Unlock()
+	if w.manifests == nil {
+		w.manifests = map[string]*unstructured.Unstructured{}
+	}
+	w.manifests[targetKey(obj)] = obj.DeepCopy()
+}
+
+// forgetManifest removes the recorded manifest of the given object.
+func (w *ItemWatcher) forgetManifest(obj client.Object) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	w.manifestsMu.Lock()
+	defer w.manifestsMu.Unlock()
+	delete(w.manifests, targetKey(u))
+}
+
+// differsFromManifest returns true if a field of the recorded manifest of the given object has another value in the
+// object. Objects without recorded manifest have not been synced since espejo started, they are reconciled shortly.
+func (w *ItemWatcher) differsFromManifest(obj client.Object) bool {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return true
+	}
+	w.manifestsMu.RLock()
+	defer w.manifestsMu.RUnlock()
+	manifest, found := w.manifests[targetKey(u)]
+	return found && !containsManifest(u.Object, manifest.Object)
+}
+
+// driftPredicate filters the events of synced objects that may have drifted from their sync item.
+// Creations are ignored, as they either stem from espejo itself or from the initial listing of the cache.
+// Updates are only passed if a field of the manifest the object has been synced with changed, changes to other fields,
+// e.g. annotations of other controllers or fields defaulted by the API server, are left alone.
+func (w *ItemWatcher) driftPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		CreateFunc:  func(event.CreateEvent) bool { return false },
+		GenericFunc: func(event.GenericEvent) bool { return false },
+		DeleteFunc:  func(event.DeleteEvent) bool { return true },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return hasDrifted(e.ObjectOld, e.ObjectNew) && w.differsFromManifest(e.ObjectNew)
+		},
+	}
+}
+
+// driftHandler enqueues the requests of mapToSyncConfigRequest for drifted and deleted objects.
+// Repeated drift of the same object is enqueued with the delay of driftBackoff.
+func (w *ItemWatcher) driftHandler() handler.EventHandler {
+	enqueue := func(ctx context.Context, obj client.Object, q workqueue.RateLimitingInterface) {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			return
+		}
+		delay := w.backoff.next(targetKey(u), time.Now())
+		for _, req := range mapToSyncConfigRequest(ctx, obj) {
+			q.AddAfter(req, delay)
+		}
+	}
+	return handler.Funcs{
+		UpdateFunc: func(ctx context.Context, e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+			enqueue(ctx, e.ObjectNew, q)
+		},
+		DeleteFunc: func(ctx context.Context, e event.DeleteEvent, q workqueue.RateLimitingInterface) {
+			w.forgetManifest(e.Object)
+			enqueue(ctx, e.Object, q)
+		},
+	}
+}
+
+// next returns the delay for correcting the drift of the object with the given key at the given time.
+// The first drift is corrected immediately, the delay of each further drift is doubled up to driftBackoffMax.
+func (b *driftBackoff) next(key string, now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.entries == nil {
+		b.entries = map[string]*driftBackoffEntry{}
+	}
+	if now.Sub(b.swept) > driftBackoffMax {
+		for k, entry := range b.entries {
+			if now.Sub(entry.last) > driftBackoffMax {
+				delete(b.entries, k)
+			}
+		}
+		b.swept = now
+	}
+	entry, found := b.entries[key]
+	if !found || now.Sub(entry.last) > driftBackoffMax {
+		b.entries[key] = &driftBackoffEntry{last: now}
+		return 0
+	}
+	entry.delay = min(max(2*entry.delay, driftBackoffBase), driftBackoffMax)
+	entry.last = now
+	return entry.delay
+}
+
+// containsManifest returns true if every field of the given manifest has the same value in the given live object.
+// Fields that only exist in the live object are ignored. Fields of the manifest with an empty value match missing
+// fields, since the API server drops them. Lists must have the same length, their elements are compared the same way.
+func containsManifest(live, manifest interface{}) bool {
+	switch m := manifest.(type) {
+	case map[string]interface{}:
+		l, ok := live.(map[string]interface{})
+		if !ok {
+			return live == nil && len(m) == 0
+		}
+		for key, value := range m {
+			liveValue, found := l[key]
+			if !found {
+				if !isEmptyValue(value) {
+					return false
+				}
+				continue
+			}
+			if !containsManifest(liveValue, value) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		l, ok := live.([]interface{})
+		if !ok {
+			return live == nil && len(m) == 0
+		}
+		if len(l) != len(m) {
+			return false
+		}
+		for i := range m {
+			if !containsManifest(l[i], m[i]) {
+				return false
+			}
+		}
+		return true
+	case nil:
+		return true
+	}
+	if liveNumber, ok := toFloat(live); ok {
+		manifestNumber, ok := toFloat(manifest)
+		return ok && liveNumber == manifestNumber
+	}
+	return equality.Semantic.DeepEqual(live, manifest)
+}
+
+// isEmptyValue returns true if the given value is the zero value of its type or an empty map or list.
+func isEmptyValue(v interface{}) bool {
+	switch typed := v.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		return len(typed) == 0
+	case []interface{}:
+		return len(typed) == 0
+	case string:
+		return typed == ""
+	case bool:
+		return !typed
+	}
+	f, ok := toFloat(v)
+	return ok && f == 0
+}
+
+// toFloat returns the given number as float64, as numbers may be decoded as integers or floats.
+func toFloat(v interface{}) (float64, bool) {
+	switch typed := v.(type) {
+	case int64:
+		return float64(typed), true
+	case int32:
+		return float64(typed), true
+	case int:
+		return float64(typed), true
+	case float64:
+		return typed, true
+	}
+	return 0, false
+}
+
+// hasDrifted returns true if the given objects differ in more than their status or server managed metadata.
+func hasDrifted(oldObj, newObj client.Object) bool {
+	oldU, okOld := oldObj.(*unstructured.Unstructured)
+	newU, okNew := newObj.(*unstructured.Unstructured)
+	if !okOld || !okNew {
+		return true
+	}
+	return !equality.Semantic.DeepEqual(withoutServerFields(oldU), withoutServerFields(newU))
+}
+
+func withoutServerFields(obj *unstructured.Unstructured) map[string]interface{} {
+	c := obj.DeepCopy()
+	unstructured.RemoveNestedField(c.Object, "status")
+	unstructured.RemoveNestedField(c.Object, "metadata", "resourceVersion")
+	unstructured.RemoveNestedField(c.Object, "metadata", "managedFields")
+	unstructured.RemoveNestedField(c.Object, "metadata", "generation")
+	return c.Object
+}
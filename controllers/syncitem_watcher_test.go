@@ -0,0 +1,268 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
+)
+
+func Test_HasDrifted(t *testing.T) {
+	original := toUnstructured(t, &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns", ResourceVersion: "1"},
+		Data:       map[string]string{"key": "value"},
+	})
+	tests := map[string]struct {
+		modify   func(obj *unstructured.Unstructured)
+		expected bool
+	}{
+		"GivenDataChange_ThenDrifted": {
+			modify: func(obj *unstructured.Unstructured) {
+				_ = unstructured.SetNestedField(obj.Object, "other", "data", "key")
+			},
+			expected: true,
+		},
+		"GivenLabelChange_ThenDrifted": {
+			modify: func(obj *unstructured.Unstructured) {
+				obj.SetLabels(map[string]string{"app": "test"})
+			},
+			expected: true,
+		},
+		"GivenResourceVersionChange_ThenNotDrifted": {
+			modify: func(obj *unstructured.Unstructured) {
+				obj.SetResourceVersion("2")
+			},
+			expected: false,
+		},
+		"GivenStatusChange_ThenNotDrifted": {
+			modify: func(obj *unstructured.Unstructured) {
+				_ = unstructured.SetNestedField(obj.Object, "Ready", "status", "phase")
+			},
+			expected: false,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			modified := original.DeepCopy()
+			tt.modify(modified)
+			assert.Equal(t, tt.expected, hasDrifted(&original, modified))
+		})
+	}
+}
+
+func Test_MapToNamespaceRequest(t *testing.T) {
+	obj := toUnstructured(t, &corev1.ConfigMap{ObjectMeta: toObjectMeta("test", "target")})
+
+	requests := mapToNamespaceRequest(context.TODO(), &obj)
+
+	assert.Equal(t, []ctrl.Request{{NamespacedName: types.NamespacedName{Name: "target"}}}, requests)
+}
+
+func Test_MapToSyncConfigRequest(t *testing.T) {
+	obj := toUnstructured(t, &corev1.ConfigMap{ObjectMeta: toObjectMeta("test", "target")})
+	require.NoError(t, setOwnershipMetadata(&syncv1beta1.SyncConfig{ObjectMeta: toObjectMeta("config", "espejo")}, &obj, nil))
+
+	requests := mapToSyncConfigRequest(context.TODO(), &obj)
+
+	require.Len(t, requests, 1)
+	syncConfig, namespace, scoped := parseScopedRequest(requests[0])
+	assert.True(t, scoped)
+	assert.Equal(t, types.NamespacedName{Namespace: "espejo", Name: "config"}, syncConfig)
+	assert.Equal(t, "target", namespace)
+}
+
+func Test_MapToSyncConfigRequest_GivenNoOwnerAnnotations_ThenMapToNamespace(t *testing.T) {
+	obj := toUnstructured(t, &corev1.ConfigMap{ObjectMeta: toObjectMeta("test", "target")})
+
+	requests := mapToSyncConfigRequest(context.TODO(), &obj)
+
+	assert.Equal(t, []ctrl.Request{{NamespacedName: types.NamespacedName{Name: "target"}}}, requests)
+	_, _, scoped := parseScopedRequest(requests[0])
+	assert.False(t, scoped)
+}
+
+func Test_DriftPredicate(t *testing.T) {
+	manifest := toUnstructured(t, &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns", Annotations: map[string]string{"note": "kept"}},
+		Data:       map[string]string{"key": "value"},
+	})
+	unstructured.RemoveNestedField(manifest.Object, "metadata", "creationTimestamp")
+	require.NoError(t, setOwnershipMetadata(&syncv1beta1.SyncConfig{ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "espejo", UID: "uid"}}, &manifest, nil))
+	live := manifest.DeepCopy()
+	live.SetUID("object-uid")
+	live.SetResourceVersion("1")
+	live.SetCreationTimestamp(metav1.Now())
+	live.SetManagedFields([]metav1.ManagedFieldsEntry{{Manager: "espejo"}})
+
+	tests := map[string]struct {
+		modify   func(obj *unstructured.Unstructured)
+		unknown  bool
+		expected bool
+	}{
+		"GivenUnmanagedAnnotationAdded_ThenNoDrift": {
+			modify: func(obj *unstructured.Unstructured) {
+				annotations := obj.GetAnnotations()
+				annotations["other-controller/revision"] = "2"
+				obj.SetAnnotations(annotations)
+			},
+			expected: false,
+		},
+		"GivenUnmanagedFieldAdded_ThenNoDrift": {
+			modify: func(obj *unstructured.Unstructured) {
+				obj.SetFinalizers([]string{"other-controller/cleanup"})
+			},
+			expected: false,
+		},
+		"GivenManagedAnnotationChanged_ThenDrifted": {
+			modify: func(obj *unstructured.Unstructured) {
+				annotations := obj.GetAnnotations()
+				annotations["note"] = "changed"
+				obj.SetAnnotations(annotations)
+			},
+			expected: true,
+		},
+		"GivenManagedFieldChanged_ThenDrifted": {
+			modify: func(obj *unstructured.Unstructured) {
+				_ = unstructured.SetNestedField(obj.Object, "other", "data", "key")
+			},
+			expected: true,
+		},
+		"GivenManagedFieldRemoved_ThenDrifted": {
+			modify: func(obj *unstructured.Unstructured) {
+				unstructured.RemoveNestedField(obj.Object, "data", "key")
+			},
+			expected: true,
+		},
+		"GivenUnknownManifest_ThenNoDrift": {
+			modify: func(obj *unstructured.Unstructured) {
+				_ = unstructured.SetNestedField(obj.Object, "other", "data", "key")
+			},
+			unknown:  true,
+			expected: false,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			w := &ItemWatcher{}
+			if !tt.unknown {
+				w.recordManifest(&manifest)
+			}
+			updated := live.DeepCopy()
+			tt.modify(updated)
+			updated.SetResourceVersion("2")
+
+			result := w.driftPredicate().Update(event.UpdateEvent{ObjectOld: live.DeepCopy(), ObjectNew: updated})
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func Test_DriftHandler_GivenUnmanagedAnnotationChanged_ThenNoReconcileQueued(t *testing.T) {
+	manifest := toUnstructured(t, &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns"},
+		Data:       map[string]string{"key": "value"},
+	})
+	require.NoError(t, setOwnershipMetadata(&syncv1beta1.SyncConfig{ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "espejo", UID: "uid"}}, &manifest, nil))
+	w := &ItemWatcher{}
+	w.recordManifest(&manifest)
+	q := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer q.ShutDown()
+
+	// Emulate the controller, which only passes the events accepted by the predicate to the handler.
+	update := func(old, new *unstructured.Unstructured) {
+		e := event.UpdateEvent{ObjectOld: old, ObjectNew: new}
+		if w.driftPredicate().Update(e) {
+			w.driftHandler().Update(context.TODO(), e, q)
+		}
+	}
+	live := manifest.DeepCopy()
+	live.SetResourceVersion("1")
+	annotated := live.DeepCopy()
+	annotations := annotated.GetAnnotations()
+	annotations["other-controller/revision"] = "2"
+	annotated.SetAnnotations(annotations)
+	annotated.SetResourceVersion("2")
+	update(live, annotated)
+	assert.Equal(t, 0, q.Len())
+
+	drifted := annotated.DeepCopy()
+	_ = unstructured.SetNestedField(drifted.Object, "other", "data", "key")
+	drifted.SetResourceVersion("3")
+	update(annotated, drifted)
+	assert.Equal(t, 1, q.Len())
+}
+
+func Test_DriftBackoff(t *testing.T) {
+	b := &driftBackoff{}
+	now := time.Now()
+
+	assert.Equal(t, time.Duration(0), b.next("ns/test", now), "first drift")
+	assert.Equal(t, driftBackoffBase, b.next("ns/test", now.Add(time.Second)))
+	assert.Equal(t, 2*driftBackoffBase, b.next("ns/test", now.Add(2*time.Second)))
+	assert.Equal(t, time.Duration(0), b.next("ns/other", now.Add(2*time.Second)), "other object")
+
+	for i := 0; i < 20; i++ {
+		now = now.Add(time.Minute)
+		b.next("ns/test", now)
+	}
+	assert.Equal(t, driftBackoffMax, b.next("ns/test", now), "capped delay")
+	assert.Equal(t, time.Duration(0), b.next("ns/test", now.Add(driftBackoffMax+time.Second)), "reset after quiet period")
+}
+
+func Test_ContainsManifest(t *testing.T) {
+	tests := map[string]struct {
+		live     interface{}
+		manifest interface{}
+		expected bool
+	}{
+		"GivenAdditionalLiveField_ThenContained": {
+			live:     map[string]interface{}{"a": "1", "b": "2"},
+			manifest: map[string]interface{}{"a": "1"},
+			expected: true,
+		},
+		"GivenMissingEmptyField_ThenContained": {
+			live:     map[string]interface{}{"a": "1"},
+			manifest: map[string]interface{}{"a": "1", "b": map[string]interface{}{}, "c": false},
+			expected: true,
+		},
+		"GivenMissingField_ThenNotContained": {
+			live:     map[string]interface{}{"a": "1"},
+			manifest: map[string]interface{}{"a": "1", "b": "2"},
+			expected: false,
+		},
+		"GivenNumbersOfDifferentTypes_ThenContained": {
+			live:     map[string]interface{}{"replicas": int64(3)},
+			manifest: map[string]interface{}{"replicas": float64(3)},
+			expected: true,
+		},
+		"GivenListOfDifferentLength_ThenNotContained": {
+			live:     []interface{}{"a", "b"},
+			manifest: []interface{}{"a"},
+			expected: false,
+		},
+		"GivenListWithDefaultedFields_ThenContained": {
+			live:     []interface{}{map[string]interface{}{"name": "a", "protocol": "TCP"}},
+			manifest: []interface{}{map[string]interface{}{"name": "a"}},
+			expected: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, containsManifest(tt.live, tt.manifest))
+		})
+	}
+}
@@ -0,0 +1,291 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"sigs.k8s.io/yaml"
+)
+
+// templateName is the name of the Go templates in the error messages.
+const templateName = "item"
+
+var (
+	// templateAction matches the actions of a Go template.
+	templateAction = regexp.MustCompile(`(?s){{.*?}}`)
+	// templateActionToken matches the tokens that stand in for the template actions while an item is serialized.
+	templateActionToken = regexp.MustCompile(`espejo-template-action-(\d+)`)
+)
+
+// templateData is the data that the Go templates in the sync items are executed with.
+type templateData struct {
+	// Namespace is the target namespace.
+	Namespace corev1.Namespace
+	// SyncConfig is the metadata of the SyncConfig.
+	SyncConfig metav1.ObjectMeta
+	// Parameters holds the values of the placeholders without arguments by name, e.g. PROJECT_NAME.
+	// Placeholders without a value are missing.
+	Parameters map[string]string
+}
+
+// templateFuncs are the functions available in Go templates, a subset of the Sprig functions commonly used in
+// Helm charts. Arguments are in the same order as in Sprig, so that values can be piped into the last argument.
+var templateFuncs = template.FuncMap{
+	"default":    defaultValue,
+	"empty":      isEmpty,
+	"coalesce":   coalesce,
+	"required":   required,
+	"ternary":    ternary,
+	"upper":      strings.ToUpper,
+	"lower":      strings.ToLower,
+	"trim":       strings.TrimSpace,
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"replace":    func(old, replacement, s string) string { return strings.ReplaceAll(s, old, replacement) },
+	"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+	"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+	"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+	"trunc":      trunc,
+	"quote":      func(s interface{}) string { return fmt.Sprintf("%q", fmt.Sprint(s)) },
+	"squote":     func(s interface{}) string { return "'" + fmt.Sprint(s) + "'" },
+	"indent":     func(n int, s string) string { return indent(n, s) },
+	"nindent":    func(n int, s string) string { return "\n" + indent(n, s) },
+	"join":       join,
+	"splitList":  func(sep, s string) []string { return strings.Split(s, sep) },
+	"list":       func(values ...interface{}) []interface{} { return values },
+	"dict":       dict,
+	"hasKey":     hasKey,
+	"toJson":     toJSON,
+	"toYaml":     toYAML,
+	"b64enc":     func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+	"b64dec":     b64dec,
+	"sha256sum":  func(s string) string { sum := sha256.Sum256([]byte(s)); return hex.EncodeToString(sum[:]) },
+}
+
+// newTemplateData returns the data for the Go templates of the sync items rendered with the given placeholder values.
+func newTemplateData(values placeholderValues) templateData {
+	return templateData{
+		Namespace:  values.namespace,
+		SyncConfig: values.cfg.ObjectMeta,
+		Parameters: values.parameters(),
+	}
+}
+
+// execute renders the given string as Go template with this data.
+func (d templateData) execute(s string) (string, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+	tmpl, err := parseTemplate(s)
+	if err != nil {
+		return s, err
+	}
+	out := strings.Builder{}
+	if err := tmpl.Execute(&out, d); err != nil {
+		return s, err
+	}
+	return out.String(), nil
+}
+
+// parseTemplate parses the given string as Go template with the template functions.
+func parseTemplate(s string) (*template.Template, error) {
+	return template.New(templateName).Option("missingkey=zero").Funcs(templateFuncs).Parse(s)
+}
+
+// render renders the given sync item as a single Go template with this data and returns the resulting object.
+// The item is serialized as YAML, executed as template and the result is parsed as YAML again.
+func (d templateData) render(m map[string]interface{}) (map[string]interface{}, error) {
+	text, err := itemTemplate(m)
+	if err != nil {
+		return nil, err
+	}
+	out, err := d.execute(text)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := yaml.YAMLToJSON([]byte(out))
+	if err != nil {
+		return nil, fmt.Errorf("rendered item is not valid YAML: %w", err)
+	}
+	result := map[string]interface{}{}
+	if err := utiljson.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("rendered item is not an object: %w", err)
+	}
+	return result, nil
+}
+
+// parseItemTemplate parses the given sync item as Go template.
+func parseItemTemplate(m map[string]interface{}) error {
+	text, err := itemTemplate(m)
+	if err != nil {
+		return err
+	}
+	_, err = parseTemplate(text)
+	return err
+}
+
+// itemTemplate serializes the given sync item as YAML to be executed as a single Go template.
+// The template actions in keys and string values are kept verbatim instead of being quoted or escaped in the YAML,
+// so that an action can render any YAML, e.g. a whole map with toYaml, or a condition in a key can leave out the whole entry.
+func itemTemplate(m map[string]interface{}) (string, error) {
+	var actions []string
+	tokenized := replaceActions(m, func(s string) string {
+		return templateAction.ReplaceAllStringFunc(s, func(action string) string {
+			actions = append(actions, action)
+			return fmt.Sprintf("espejo-template-action-%d", len(actions)-1)
+		})
+	})
+	out, err := yaml.Marshal(tokenized)
+	if err != nil {
+		return "", err
+	}
+	return templateActionToken.ReplaceAllStringFunc(string(out), func(token string) string {
+		index, _ := strconv.Atoi(templateActionToken.FindStringSubmatch(token)[1])
+		if index >= len(actions) {
+			return token
+		}
+		return actions[index]
+	}), nil
+}
+
+// replaceActions returns a copy of the given value with the given replace function applied to all keys and strings.
+func replaceActions(v interface{}, replace func(string) string) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(value))
+		for key, entry := range value {
+			m[replace(key)] = replaceActions(entry, replace)
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, 0, len(value))
+		for _, entry := range value {
+			list = append(list, replaceActions(entry, replace))
+		}
+		return list
+	case string:
+		return replace(value)
+	default:
+		return v
+	}
+}
+
+// isEmpty returns true if the given value is nil, the zero value of its type or has a length of 0.
+func isEmpty(value interface{}) bool {
+	v := reflect.ValueOf(value)
+	if !v.IsValid() {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	default:
+		return v.IsZero()
+	}
+}
+
+func defaultValue(fallback, value interface{}) interface{} {
+	if isEmpty(value) {
+		return fallback
+	}
+	return value
+}
+
+func coalesce(values ...interface{}) interface{} {
+	for _, value := range values {
+		if !isEmpty(value) {
+			return value
+		}
+	}
+	return nil
+}
+
+func required(message string, value interface{}) (interface{}, error) {
+	if isEmpty(value) {
+		return nil, errors.New(message)
+	}
+	return value, nil
+}
+
+func ternary(whenTrue, whenFalse interface{}, condition bool) interface{} {
+	if condition {
+		return whenTrue
+	}
+	return whenFalse
+}
+
+func trunc(n int, s string) string {
+	if n < 0 || len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
+
+func indent(n int, s string) string {
+	padding := strings.Repeat(" ", n)
+	return padding + strings.ReplaceAll(s, "\n", "\n"+padding)
+}
+
+func join(sep string, values interface{}) (string, error) {
+	v := reflect.ValueOf(values)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return "", fmt.Errorf("join: expected a list but got %T", values)
+	}
+	parts := make([]string, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		parts = append(parts, fmt.Sprint(v.Index(i).Interface()))
+	}
+	return strings.Join(parts, sep), nil
+}
+
+func dict(keysAndValues ...interface{}) (map[string]interface{}, error) {
+	if len(keysAndValues)%2 != 0 {
+		return nil, errors.New("dict: expected an even number of arguments")
+	}
+	d := make(map[string]interface{}, len(keysAndValues)/2)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict: expected a string key but got %T", keysAndValues[i])
+		}
+		d[key] = keysAndValues[i+1]
+	}
+	return d, nil
+}
+
+func hasKey(m interface{}, key string) (bool, error) {
+	v := reflect.ValueOf(m)
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+		return false, fmt.Errorf("hasKey: expected a map but got %T", m)
+	}
+	return v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key())).IsValid(), nil
+}
+
+func toJSON(value interface{}) (string, error) {
+	out, err := json.Marshal(value)
+	return string(out), err
+}
+
+func toYAML(value interface{}) (string, error) {
+	out, err := yaml.Marshal(value)
+	return strings.TrimSuffix(string(out), "\n"), err
+}
+
+func b64dec(s string) (string, error) {
+	out, err := base64.StdEncoding.DecodeString(s)
+	return string(out), err
+}
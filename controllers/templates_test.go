@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
+)
+
+func Test_TemplateData_Execute(t *testing.T) {
+	data := newTemplateData(placeholderValues{
+		namespace: corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:        "project",
+			Labels:      map[string]string{"env": "prod", "example.com/team": "blue"},
+			Annotations: map[string]string{"owners": "alice,bob"},
+		}},
+		cfg:         &syncv1beta1.SyncConfig{ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "espejo"}},
+		clusterName: "production",
+	})
+
+	tests := map[string]struct {
+		given       string
+		expected    string
+		expectedErr string
+	}{
+		"GivenPlainString_ThenReturnUnchanged": {
+			given:    "${PROJECT_NAME} is not replaced",
+			expected: "${PROJECT_NAME} is not replaced",
+		},
+		"GivenNamespaceAndSyncConfig_ThenRender": {
+			given:    "{{ .Namespace.Name }} {{ .SyncConfig.Namespace }}/{{ .SyncConfig.Name }} {{ .Parameters.CLUSTER_NAME }}",
+			expected: "project espejo/config production",
+		},
+		"GivenConditional_ThenRender": {
+			given:    `{{ if eq .Namespace.Labels.env "prod" }}large{{ else }}small{{ end }}`,
+			expected: "large",
+		},
+		"GivenMissingLabel_ThenRenderDefault": {
+			given:    `{{ .Namespace.Labels.tier | default "standard" }} {{ index .Namespace.Labels "example.com/team" | upper }}`,
+			expected: "standard BLUE",
+		},
+		"GivenLoop_ThenRender": {
+			given:    `{{ range splitList "," .Namespace.Annotations.owners }}[{{ . }}]{{ end }}`,
+			expected: "[alice][bob]",
+		},
+		"GivenSprigFunctions_ThenRender": {
+			given:    `{{ dict "team" (index .Namespace.Labels "example.com/team") | toJson }} {{ list "a" "b" | join "-" }} {{ hasKey .Namespace.Labels "env" }}`,
+			expected: `{"team":"blue"} a-b true`,
+		},
+		"GivenRequiredValueMissing_ThenReturnError": {
+			given:       `{{ .Namespace.Labels.tier | required "label tier is required" }}`,
+			expectedErr: "label tier is required",
+		},
+		"GivenUnknownFunction_ThenReturnError": {
+			given:       `{{ unknown .Namespace.Name }}`,
+			expectedErr: `function "unknown" not defined`,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			result, err := data.execute(tt.given)
+
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func Test_TemplateData_Render(t *testing.T) {
+	data := newTemplateData(placeholderValues{
+		namespace: corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:   "project",
+			Labels: map[string]string{"env": "prod", "example.com/team": "blue"},
+		}},
+		cfg: &syncv1beta1.SyncConfig{ObjectMeta: metav1.ObjectMeta{Name: "config"}},
+	})
+
+	tests := map[string]struct {
+		given       map[string]interface{}
+		expected    map[string]interface{}
+		expectedErr string
+	}{
+		"GivenStringValues_ThenRenderStrings": {
+			given: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": `{{ index .Namespace.Labels "example.com/team" }}-config`},
+				"data":     map[string]interface{}{"env": `{{ .Namespace.Labels.env | quote }}`, "replicas": int64(2)},
+			},
+			expected: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "blue-config"},
+				"data":     map[string]interface{}{"env": "prod", "replicas": int64(2)},
+			},
+		},
+		"GivenToYaml_ThenRenderStructure": {
+			given: map[string]interface{}{
+				"metadata": map[string]interface{}{"labels": `{{ toYaml .Namespace.Labels | nindent 4 }}`},
+			},
+			expected: map[string]interface{}{
+				"metadata": map[string]interface{}{"labels": map[string]interface{}{"env": "prod", "example.com/team": "blue"}},
+			},
+		},
+		"GivenConditionAcrossKeyAndValue_ThenRenderConditionalEntry": {
+			given: map[string]interface{}{
+				"data": map[string]interface{}{
+					`{{ if eq .Namespace.Labels.env "dev" }}debug`: `true{{ end }}`,
+					"c": "kept",
+				},
+			},
+			expected: map[string]interface{}{
+				"data": map[string]interface{}{"c": "kept"},
+			},
+		},
+		"GivenTemplateInKey_ThenRenderKey": {
+			given: map[string]interface{}{
+				"data": map[string]interface{}{"{{ .SyncConfig.Name }}.yaml": "value"},
+			},
+			expected: map[string]interface{}{
+				"data": map[string]interface{}{"config.yaml": "value"},
+			},
+		},
+		"GivenRequiredValueMissing_ThenReturnError": {
+			given:       map[string]interface{}{"data": `{{ .Namespace.Labels.tier | required "label tier is required" }}`},
+			expectedErr: "label tier is required",
+		},
+		"GivenInvalidResult_ThenReturnError": {
+			given:       map[string]interface{}{"data": `{{ "[" }}`},
+			expectedErr: "rendered item is not valid YAML",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			result, err := data.render(tt.given)
+
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
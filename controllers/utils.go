@@ -1,14 +1,25 @@
 package controllers
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"strings"
 
 	"k8s.io/api/core/v1"
-	v12 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
-	"github.com/vshn/espejo/api/v1alpha1"
+	"github.com/vshn/espejo/api/v1beta1"
+)
+
+const (
+	// maxFieldManagerLength is the maximum length of a field manager name accepted by the API server.
+	maxFieldManagerLength = 128
+	// fieldManagerHashLength is the number of hex digits of the hash that replaces the tail of too long field manager names.
+	fieldManagerHashLength = 16
 )
 
 func getLoggingKeysAndValues(unstructuredObject *unstructured.Unstructured) []interface{} {
@@ -19,33 +30,107 @@ func getLoggingKeysAndValues(unstructuredObject *unstructured.Unstructured) []in
 	}
 }
 
-func getLoggingKeysAndValuesForSyncConfig(syncconfig *v1alpha1.SyncConfig) []interface{} {
+func getLoggingKeysAndValuesForSyncConfig(syncconfig *v1beta1.SyncConfig) []interface{} {
 	return []interface{}{
 		"SyncConfig", syncconfig.Namespace + "/" + syncconfig.Name,
 	}
 }
 
-// replaceProjectName recursively replaces all string occurrences that contain the ${PROJECT_NAME} as placeholder.
-// Only replaces the values of objects, does not alter the keys.
-func replaceProjectName(replacement string, m map[string]interface{}) {
+// fieldManagerFor returns the name of the field manager used to server-side apply the items of the given SyncConfig.
+// If the name exceeds the maximum length of a field manager name, its tail is replaced with a hash of the namespace
+// and name of the SyncConfig, so that SyncConfigs with long names still have distinct field managers.
+func fieldManagerFor(syncconfig *v1beta1.SyncConfig) string {
+	manager := "espejo/" + syncconfig.Namespace + "/" + syncconfig.Name
+	if len(manager) <= maxFieldManagerLength {
+		return manager
+	}
+	sum := sha256.Sum256([]byte(syncconfig.Namespace + "/" + syncconfig.Name))
+	return manager[:maxFieldManagerLength-fieldManagerHashLength-1] + "-" + hex.EncodeToString(sum[:])[:fieldManagerHashLength]
+}
+
+// setOwnershipMetadata marks the given object as synced by the given SyncConfig.
+// The hash of the rendered manifest is computed before any ownership metadata is added and without the given ignored
+// fields, so that it does not change with the values the ignored fields are preserved with.
+func setOwnershipMetadata(syncconfig *v1beta1.SyncConfig, obj *unstructured.Unstructured, ignored []ignoreDifference) error {
+	manifest := obj
+	if hasIgnoredFields(ignored, obj) {
+		manifest = obj.DeepCopy()
+		removeIgnoredFields(ignored, manifest)
+	}
+	hash, err := manifestHash(manifest)
+	if err != nil {
+		return err
+	}
+
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[v1beta1.LabelManagedBy] = v1beta1.ManagedByEspejo
+	labels[v1beta1.LabelOwnerUID] = string(syncconfig.UID)
+	obj.SetLabels(labels)
+
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[v1beta1.AnnotationOwnerNamespace] = syncconfig.Namespace
+	annotations[v1beta1.AnnotationOwnerName] = syncconfig.Name
+	annotations[v1beta1.AnnotationManifestHash] = hash
+	obj.SetAnnotations(annotations)
+	return nil
+}
+
+// manifestHash returns the hex encoded SHA-256 hash of the JSON representation of the given object.
+func manifestHash(obj *unstructured.Unstructured) (string, error) {
+	b, err := json.Marshal(obj.Object)
+	if err != nil {
+		return "", fmt.Errorf("cannot hash manifest: %w", err)
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ownedBy returns the list option that selects all objects synced by the given SyncConfig.
+func ownedBy(syncconfig *v1beta1.SyncConfig) client.MatchingLabels {
+	return client.MatchingLabels{
+		v1beta1.LabelManagedBy: v1beta1.ManagedByEspejo,
+		v1beta1.LabelOwnerUID:  string(syncconfig.UID),
+	}
+}
+
+// renderStrings recursively replaces all string values of the given object with the result of the given render function.
+// Only replaces the values of objects, does not alter the keys. The errors of the render function are returned joined.
+func renderStrings(m map[string]interface{}, render func(string) (string, error)) error {
+	var errs []error
+	replace := func(s string) string {
+		rendered, err := render(s)
+		if err != nil {
+			errs = append(errs, err)
+			return s
+		}
+		return rendered
+	}
 	for k, v := range m {
 		if v == nil {
 			continue
 		}
-		m[k] = transcendStructure(replacement, v)
+		m[k] = transcendStructure(replace, v)
 	}
+	return errors.Join(errs...)
 }
 
-func transcendStructure(replacement string, v interface{}) interface{} {
+// transcendStructure recursively applies the given replace function to all strings in the given value.
+func transcendStructure(replace func(string) string, v interface{}) interface{} {
 	if v == nil {
 		return nil
 	}
 	switch v.(type) {
 	case string:
-		return replacePlaceholders(replacement, v.(string))
+		return replace(v.(string))
 	case []string:
 		for i, a := range v.([]string) {
-			v.([]string)[i] = replacePlaceholders(replacement, a)
+			v.([]string)[i] = replace(a)
 		}
 		return v
 	case int64:
@@ -54,22 +139,24 @@ func transcendStructure(replacement string, v interface{}) interface{} {
 	case []int32:
 	case int:
 	case []int:
+	case float64:
+	case []float64:
 	case bool:
 	case []bool:
 		return v
 	case map[string]interface{}:
 		for k, m := range v.(map[string]interface{}) {
-			v.(map[string]interface{})[k] = transcendStructure(replacement, m)
+			v.(map[string]interface{})[k] = transcendStructure(replace, m)
 		}
 		return v
 	case []map[string]interface{}:
 		for k, m := range v.([]map[string]interface{}) {
-			v.([]map[string]interface{})[k] = transcendStructure(replacement, m).(map[string]interface{})
+			v.([]map[string]interface{})[k] = transcendStructure(replace, m).(map[string]interface{})
 		}
 		return v
 	case []interface{}:
 		for i, a := range v.([]interface{}) {
-			v.([]interface{})[i] = transcendStructure(replacement, a)
+			v.([]interface{})[i] = transcendStructure(replace, a)
 		}
 		return v
 	default:
@@ -78,14 +165,10 @@ func transcendStructure(replacement string, v interface{}) interface{} {
 	return v
 }
 
-func replacePlaceholders(replacement, s string) string {
-	return strings.ReplaceAll(s, "${PROJECT_NAME}", replacement)
-}
-
 func namespaceFromString(namespace string) v1.Namespace {
 	return v1.Namespace{
-		TypeMeta:   v12.TypeMeta{Kind: "Namespace", APIVersion: "v1"},
-		ObjectMeta: v12.ObjectMeta{Name: namespace},
+		TypeMeta:   metav1.TypeMeta{Kind: "Namespace", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: namespace},
 	}
 }
 
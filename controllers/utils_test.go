@@ -1,8 +1,15 @@
 package controllers
 
 import (
-	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/vshn/espejo/api/v1beta1"
 )
 
 func Test_Replacement(t *testing.T) {
@@ -45,7 +52,8 @@ func Test_Replacement(t *testing.T) {
 		},
 	}
 
-	replaceProjectName(replacement, m)
+	values := placeholderValues{namespace: namespaceFromString(replacement), cfg: &v1beta1.SyncConfig{}}
+	require.NoError(t, renderStrings(m, values.replace))
 
 	assert.Equal(t, replacement, m["object-with-nested-objects"].(map[string]interface{})["object"].(map[string]interface{})["string-field"])
 	assert.Equal(t, replacement, m["slice-with-strings"].([]string)[0])
@@ -57,3 +65,63 @@ func Test_Replacement(t *testing.T) {
 	assert.Equal(t, replacement, m["slice-with-nested-slices"].([]interface{})[3].([]map[string]interface{})[0]["string-field"])
 
 }
+
+func Test_FieldManagerFor(t *testing.T) {
+	cfg := &v1beta1.SyncConfig{ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "espejo"}}
+	assert.Equal(t, "espejo/espejo/config", fieldManagerFor(cfg))
+
+	cfg.Name = strings.Repeat("a", 253)
+	manager := fieldManagerFor(cfg)
+	assert.Len(t, manager, maxFieldManagerLength)
+
+	other := cfg.DeepCopy()
+	other.Name = strings.Repeat("a", 252) + "b"
+	assert.Len(t, fieldManagerFor(other), maxFieldManagerLength)
+	assert.NotEqual(t, manager, fieldManagerFor(other))
+}
+
+func Test_SetOwnershipMetadata(t *testing.T) {
+	cfg := &v1beta1.SyncConfig{ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "espejo", UID: "1234"}}
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("ConfigMap")
+	obj.SetName("test")
+	obj.SetLabels(map[string]string{"app": "test"})
+	expectedHash, err := manifestHash(obj)
+	require.NoError(t, err)
+
+	require.NoError(t, setOwnershipMetadata(cfg, obj, nil))
+
+	assert.Equal(t, map[string]string{
+		"app":                  "test",
+		v1beta1.LabelManagedBy: v1beta1.ManagedByEspejo,
+		v1beta1.LabelOwnerUID:  "1234",
+	}, obj.GetLabels())
+	assert.Equal(t, map[string]string{
+		v1beta1.AnnotationOwnerNamespace: "espejo",
+		v1beta1.AnnotationOwnerName:      "config",
+		v1beta1.AnnotationManifestHash:   expectedHash,
+	}, obj.GetAnnotations())
+	assert.Len(t, expectedHash, 64)
+}
+
+func Test_SetOwnershipMetadata_GivenIgnoredFields_ThenHashWithoutIgnoredFields(t *testing.T) {
+	cfg := &v1beta1.SyncConfig{ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "espejo", UID: "1234"}}
+	rendered := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "app"},
+		"spec":       map[string]interface{}{"replicas": int64(1), "paused": false},
+	}}
+	scaled := rendered.DeepCopy()
+	_ = unstructured.SetNestedField(scaled.Object, int64(5), "spec", "replicas")
+	ignored, err := parseIgnoreDifferences([]v1beta1.IgnoreDifference{{Kind: "Deployment", JSONPointers: []string{"/spec/replicas"}}})
+	require.NoError(t, err)
+
+	require.NoError(t, setOwnershipMetadata(cfg, rendered, ignored))
+	require.NoError(t, setOwnershipMetadata(cfg, scaled, ignored))
+
+	assert.Equal(t, rendered.GetAnnotations()[v1beta1.AnnotationManifestHash], scaled.GetAnnotations()[v1beta1.AnnotationManifestHash])
+	replicas, _, _ := unstructured.NestedInt64(scaled.Object, "spec", "replicas")
+	assert.Equal(t, int64(5), replicas, "ignored fields are kept in the object")
+}
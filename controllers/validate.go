@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
+)
+
+// ValidationFinding is a problem found in a SyncConfig manifest.
+type ValidationFinding struct {
+	// Field is the path of the offending field in the SyncConfig, e.g. `spec.syncItems[1]`.
+	Field string `json:"field"`
+	// Message describes the problem.
+	Message string `json:"message"`
+}
+
+// ValidateSyncConfig checks the given SyncConfig without a cluster and returns all problems found.
+// In addition to the checks done at reconcile time, sync items of kinds known to the given scheme are decoded into their
+// types to find unknown fields and wrong apiVersions, and sync items are checked for duplicates and for collisions
+// with delete items, unless they have a namespace selector or a when expression. Sync items of kinds unknown to the
+// scheme, e.g. custom resources, are only checked for apiVersion, kind and name.
+func ValidateSyncConfig(cfg *syncv1beta1.SyncConfig, scheme *runtime.Scheme) []ValidationFinding {
+	findings := make([]ValidationFinding, 0)
+	rc := &ReconciliationContext{cfg: cfg}
+	if err := rc.validateSpec(); err != nil {
+		findings = append(findings, ValidationFinding{Field: "spec", Message: err.Error()})
+	}
+
+	syncItems := map[string]string{}
+	for i, item := range cfg.Spec.SyncItems {
+		field := fmt.Sprintf("spec.syncItems[%d]", i)
+		for _, msg := range validateSyncItem(&item.Manifest.Unstructured, scheme) {
+			findings = append(findings, ValidationFinding{Field: field, Message: msg})
+		}
+		if item.NamespaceSelector != nil || item.When != "" {
+			// The item may target other namespaces than an item with the same name.
+			continue
+		}
+		key := itemKey(item.Manifest.GetAPIVersion(), item.Manifest.GetKind(), item.Manifest.GetName())
+		if other, found := syncItems[key]; found {
+			findings = append(findings, ValidationFinding{Field: field, Message: "duplicates " + other})
+			continue
+		}
+		syncItems[key] = field
+	}
+
+	for i, item := range cfg.Spec.DeleteItems {
+		field := fmt.Sprintf("spec.deleteItems[%d]", i)
+		if item.APIVersion == "" || item.Kind == "" || item.Name == "" {
+			findings = append(findings, ValidationFinding{Field: field, Message: "apiVersion, kind and name are required"})
+			continue
+		}
+		if msg := validateAPIVersion(item.APIVersion, item.Kind, scheme); msg != "" {
+			findings = append(findings, ValidationFinding{Field: field, Message: msg})
+		}
+		if other, found := syncItems[itemKey(item.APIVersion, item.Kind, item.Name)]; found && item.NamespaceSelector == nil {
+			findings = append(findings, ValidationFinding{Field: field, Message: "deletes the object synced by " + other})
+		}
+	}
+	return findings
+}
+
+// validateSyncItem returns the problems of the given sync item.
+func validateSyncItem(item *unstructured.Unstructured, scheme *runtime.Scheme) []string {
+	var problems []string
+	if item.GetAPIVersion() == "" || item.GetKind() == "" {
+		return append(problems, "apiVersion and kind are required")
+	}
+	if item.GetName() == "" {
+		problems = append(problems, "metadata.name is required")
+	}
+	if msg := validateAPIVersion(item.GetAPIVersion(), item.GetKind(), scheme); msg != "" {
+		return append(problems, msg)
+	}
+	gvk := item.GroupVersionKind()
+	if !scheme.Recognizes(gvk) {
+		return problems
+	}
+	typed, err := scheme.New(gvk)
+	if err != nil {
+		return append(problems, err.Error())
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructuredWithValidation(item.Object, typed, true); err != nil {
+		problems = append(problems, err.Error())
+	}
+	return problems
+}
+
+// validateAPIVersion returns a problem if the given apiVersion is malformed, or if its group is known to the scheme but
+// the kind does not exist in the given apiVersion. An empty string is returned otherwise.
+func validateAPIVersion(apiVersion, kind string, scheme *runtime.Scheme) string {
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return fmt.Sprintf("apiVersion %q is invalid: %v", apiVersion, err)
+	}
+	if scheme.Recognizes(gv.WithKind(kind)) || !scheme.IsGroupRegistered(gv.Group) {
+		return ""
+	}
+	var known []string
+	for gvk := range scheme.AllKnownTypes() {
+		if gvk.Kind == kind && gvk.Version != runtime.APIVersionInternal {
+			known = append(known, gvk.GroupVersion().String())
+		}
+	}
+	if len(known) == 0 {
+		return fmt.Sprintf("kind %s does not exist in apiVersion %q", kind, apiVersion)
+	}
+	sort.Strings(known)
+	return fmt.Sprintf("kind %s does not exist in apiVersion %q, use one of %s", kind, apiVersion, strings.Join(known, ", "))
+}
+
+// itemKey identifies an object by its group, kind and name, since the same object can be addressed in multiple versions.
+func itemKey(apiVersion, kind, name string) string {
+	gv, _ := schema.ParseGroupVersion(apiVersion)
+	return gv.Group + "/" + kind + "/" + name
+}
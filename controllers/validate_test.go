@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
+)
+
+func Test_ValidateSyncConfig(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	manifest := func(apiVersion, kind, name string, fields map[string]interface{}) syncv1beta1.SyncItem {
+		obj := unstructured.Unstructured{Object: fields}
+		if obj.Object == nil {
+			obj.Object = map[string]interface{}{}
+		}
+		obj.SetAPIVersion(apiVersion)
+		obj.SetKind(kind)
+		if name != "" {
+			obj.SetName(name)
+		}
+		return syncv1beta1.SyncItem{Manifest: syncv1beta1.Manifest{Unstructured: obj}}
+	}
+	withSelector := func(item syncv1beta1.SyncItem, key, value string) syncv1beta1.SyncItem {
+		item.NamespaceSelector = &syncv1beta1.NamespaceSelector{LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{key: value}}}
+		return item
+	}
+
+	tests := map[string]struct {
+		givenSyncItems   []syncv1beta1.SyncItem
+		givenDeleteItems []syncv1beta1.DeleteMeta
+		expectedFindings []ValidationFinding
+	}{
+		"GivenValidItems_ThenNoFindings": {
+			givenSyncItems: []syncv1beta1.SyncItem{
+				manifest("v1", "ConfigMap", "config", map[string]interface{}{"data": map[string]interface{}{"key": "${PROJECT_NAME}"}}),
+				manifest("example.com/v1", "Custom", "custom", map[string]interface{}{"spec": "anything"}),
+			},
+			givenDeleteItems: []syncv1beta1.DeleteMeta{{APIVersion: "v1", Kind: "Secret", Name: "config"}},
+			expectedFindings: []ValidationFinding{},
+		},
+		"GivenItemWithoutName_ThenReportMissingName": {
+			givenSyncItems:   []syncv1beta1.SyncItem{manifest("v1", "ConfigMap", "", nil)},
+			expectedFindings: []ValidationFinding{{Field: "spec.syncItems[0]", Message: "metadata.name is required"}},
+		},
+		"GivenUnknownField_ThenReportUnknownField": {
+			givenSyncItems:   []syncv1beta1.SyncItem{manifest("v1", "ConfigMap", "config", map[string]interface{}{"spec": map[string]interface{}{}})},
+			expectedFindings: []ValidationFinding{{Field: "spec.syncItems[0]", Message: `strict decoding error: unknown field "spec"`}},
+		},
+		"GivenApiVersionWithoutVersion_ThenReportKnownVersions": {
+			givenSyncItems: []syncv1beta1.SyncItem{manifest("networking.k8s.io", "NetworkPolicy", "policy", nil)},
+			expectedFindings: []ValidationFinding{{Field: "spec.syncItems[0]",
+				Message: `kind NetworkPolicy does not exist in apiVersion "networking.k8s.io", use one of extensions/v1beta1, networking.k8s.io/v1`}},
+		},
+		"GivenDuplicateItems_ThenReportDuplicate": {
+			givenSyncItems: []syncv1beta1.SyncItem{
+				manifest("apps/v1", "Deployment", "app", nil),
+				manifest("apps/v1", "Deployment", "app", nil),
+			},
+			expectedFindings: []ValidationFinding{{Field: "spec.syncItems[1]", Message: "duplicates spec.syncItems[0]"}},
+		},
+		"GivenDeleteItemOfSyncItem_ThenReportCollision": {
+			givenSyncItems:   []syncv1beta1.SyncItem{manifest("v1", "ConfigMap", "config", nil)},
+			givenDeleteItems: []syncv1beta1.DeleteMeta{{APIVersion: "v1", Kind: "ConfigMap", Name: "config"}},
+			expectedFindings: []ValidationFinding{{Field: "spec.deleteItems[0]", Message: "deletes the object synced by spec.syncItems[0]"}},
+		},
+		"GivenItemsWithNamespaceSelectors_ThenNoDuplicateOrCollision": {
+			givenSyncItems: []syncv1beta1.SyncItem{
+				withSelector(manifest("v1", "ConfigMap", "config", nil), "tier", "prod"),
+				withSelector(manifest("v1", "ConfigMap", "config", nil), "tier", "dev"),
+			},
+			givenDeleteItems: []syncv1beta1.DeleteMeta{{
+				APIVersion:        "v1",
+				Kind:              "ConfigMap",
+				Name:              "config",
+				NamespaceSelector: &syncv1beta1.NamespaceSelector{MatchNames: []string{"legacy-.*"}},
+			}},
+			expectedFindings: []ValidationFinding{},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := &syncv1beta1.SyncConfig{Spec: syncv1beta1.SyncConfigSpec{
+				NamespaceSelector: &syncv1beta1.NamespaceSelector{MatchNames: []string{"default"}},
+				SyncItems:         tt.givenSyncItems,
+				DeleteItems:       tt.givenDeleteItems,
+			}}
+
+			findings := ValidateSyncConfig(cfg, scheme)
+
+			assert.Equal(t, tt.expectedFindings, findings)
+		})
+	}
+}
+
+func Test_ValidateSyncConfig_GivenInvalidSpec_ThenReportSpec(t *testing.T) {
+	cfg := &syncv1beta1.SyncConfig{}
+
+	findings := ValidateSyncConfig(cfg, runtime.NewScheme())
+
+	assert.Equal(t, []ValidationFinding{{Field: "spec", Message: "either .spec.namespaceSelector.matchNames or .spec.namespaceSelector.labelSelector is required"}}, findings)
+}
@@ -0,0 +1,387 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-logr/logr"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// caValidity and certValidity are the validity periods of generated CA and serving certificates.
+	caValidity   = 10 * 365 * 24 * time.Hour
+	certValidity = 365 * 24 * time.Hour
+	// caRenewBefore and certRenewBefore define how long before their expiry the CA and serving certificates are renewed.
+	caRenewBefore   = 365 * 24 * time.Hour
+	certRenewBefore = 30 * 24 * time.Hour
+
+	// secretKeyCACert and secretKeyCAKey are the keys of the CA in the certificate Secret.
+	secretKeyCACert = "ca.crt"
+	secretKeyCAKey  = "ca.key"
+	// secretKeyPreviousCACert is the key of the CA that has been replaced by the last CA renewal.
+	secretKeyPreviousCACert = "previous-ca.crt"
+)
+
+// The certificate Secret lives in the namespace of the operator, "system" is replaced by kustomize.
+// Creations cannot be restricted to a name, the default name of the Secret is set for the other verbs.
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=create,namespace=system
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;update,resourceNames=espejo-webhook-server-cert,namespace=system
+// +kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=validatingwebhookconfigurations,verbs=get;patch
+// +kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get;patch
+
+// WebhookCertRotator generates a self-signed CA and a serving certificate for the webhook server, stores them in a
+// Secret and injects the CA into the webhook configurations of espejo.
+// Certificates are renewed before they expire. Since the CA is renewed much less often than the serving certificate,
+// multiple replicas can share the Secret without rejecting each other's certificates.
+// When the CA is renewed, the previous CA stays in the injected CA bundle until it expires, so that replicas still
+// serving a certificate signed by it are accepted until they load the renewed certificate.
+type WebhookCertRotator struct {
+	// Client is used to manage the Secret and the webhook configurations. It must not be cached,
+	// as the certificates are required before the manager starts.
+	Client client.Client
+	Log    logr.Logger
+	// Secret is the Secret in which the certificates are stored.
+	Secret types.NamespacedName
+	// DNSNames are the names of the webhook Service the serving certificate is valid for.
+	DNSNames []string
+	// CertDir is the directory the webhook server reads its certificate from.
+	CertDir string
+	// ValidatingWebhookConfiguration is the name of the ValidatingWebhookConfiguration to inject the CA into.
+	ValidatingWebhookConfiguration string
+	// CustomResourceDefinitions are the names of the CustomResourceDefinitions whose conversion webhook to inject the CA into.
+	CustomResourceDefinitions []string
+	// Interval is the interval in which the certificates are checked.
+	Interval time.Duration
+
+	now func() time.Time
+}
+
+// Start checks the certificates periodically until the given context is done.
+func (r *WebhookCertRotator) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := r.EnsureCerts(ctx); err != nil {
+				r.Log.Error(err, "Could not rotate webhook certificates")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection returns false, since every replica needs to write the certificates for its webhook server.
+func (r *WebhookCertRotator) NeedLeaderElection() bool {
+	return false
+}
+
+// EnsureCerts generates or renews the certificates if required, injects the CA bundle into the webhook configurations
+// and writes the serving certificate to CertDir.
+// The CA bundle is injected first, so that the API server trusts a renewed CA before the certificate it signed is served.
+func (r *WebhookCertRotator) EnsureCerts(ctx context.Context) error {
+	secret, err := r.ensureSecret(ctx)
+	if err != nil {
+		return fmt.Errorf("could not ensure certificate Secret: %w", err)
+	}
+	caBundle := r.caBundle(secret.Data)
+	if err := r.injectWebhookConfiguration(ctx, caBundle); err != nil {
+		return fmt.Errorf("could not inject CA into ValidatingWebhookConfiguration: %w", err)
+	}
+	for _, name := range r.CustomResourceDefinitions {
+		if err := r.injectCustomResourceDefinition(ctx, name, caBundle); err != nil {
+			return fmt.Errorf("could not inject CA into CustomResourceDefinition %s: %w", name, err)
+		}
+	}
+	if err := r.writeCertFiles(secret); err != nil {
+		return fmt.Errorf("could not write certificates: %w", err)
+	}
+	return nil
+}
+
+// caBundle returns the CA of the given Secret data followed by the previous CA, if it has not expired yet.
+func (r *WebhookCertRotator) caBundle(data map[string][]byte) []byte {
+	bundle := append([]byte{}, data[secretKeyCACert]...)
+	if previous := unexpiredCert(r.currentTime(), data[secretKeyPreviousCACert]); previous != nil {
+		bundle = append(bundle, previous...)
+	}
+	return bundle
+}
+
+// ensureSecret returns the Secret holding valid certificates, creating or updating it if required.
+// If another replica created or updated the Secret concurrently, the Secret is read again.
+func (r *WebhookCertRotator) ensureSecret(ctx context.Context) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	err := r.Client.Get(ctx, r.Secret, secret)
+	if apierrors.IsNotFound(err) {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: r.Secret.Name, Namespace: r.Secret.Namespace},
+			Type:       corev1.SecretTypeTLS,
+		}
+		if secret.Data, err = r.renewCerts(nil); err != nil {
+			return nil, err
+		}
+		r.Log.Info("Creating webhook certificates", "secret", r.Secret)
+		err = r.Client.Create(ctx, secret)
+		if apierrors.IsAlreadyExists(err) {
+			return r.ensureSecret(ctx)
+		}
+		return secret, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, err := r.renewCerts(secret.Data)
+	if err != nil || data == nil {
+		return secret, err
+	}
+	r.Log.Info("Renewing webhook certificates", "secret", r.Secret)
+	secret.Data = data
+	err = r.Client.Update(ctx, secret)
+	if apierrors.IsConflict(err) {
+		return r.ensureSecret(ctx)
+	}
+	return secret, err
+}
+
+// renewCerts returns the data of a renewed certificate Secret, or nil if the given data is still valid.
+// The CA is only renewed if it expires soon or is invalid, otherwise only the serving certificate is renewed.
+// The replaced CA is kept as previous CA until it expires, since the serving certificates it signed are valid until then.
+func (r *WebhookCertRotator) renewCerts(data map[string][]byte) (map[string][]byte, error) {
+	now := r.currentTime()
+	previousCA := unexpiredCert(now, data[secretKeyPreviousCACert])
+	ca, caKey, err := parseKeyPair(data[secretKeyCACert], data[secretKeyCAKey])
+	if err != nil || now.Add(caRenewBefore).After(ca.NotAfter) {
+		if replaced := unexpiredCert(now, data[secretKeyCACert]); replaced != nil {
+			previousCA = replaced
+		}
+		if ca, caKey, err = generateCA(now); err != nil {
+			return nil, err
+		}
+	} else if cert, _, err := parseKeyPair(data[corev1.TLSCertKey], data[corev1.TLSPrivateKeyKey]); err == nil &&
+		now.Add(certRenewBefore).Before(cert.NotAfter) && cert.VerifyHostname(r.DNSNames[0]) == nil &&
+		cert.CheckSignatureFrom(ca) == nil {
+		if previousCA != nil || len(data[secretKeyPreviousCACert]) == 0 {
+			return nil, nil
+		}
+		// The previous CA expired, only remove it from the Secret.
+		renewed := make(map[string][]byte, len(data))
+		for key, value := range data {
+			renewed[key] = value
+		}
+		delete(renewed, secretKeyPreviousCACert)
+		return renewed, nil
+	}
+
+	certPEM, keyPEM, err := generateServingCert(now, ca, caKey, r.DNSNames)
+	if err != nil {
+		return nil, err
+	}
+	caKeyPEM, err := encodeKey(caKey)
+	if err != nil {
+		return nil, err
+	}
+	renewed := map[string][]byte{
+		secretKeyCACert:         pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}),
+		secretKeyCAKey:          caKeyPEM,
+		corev1.TLSCertKey:       certPEM,
+		corev1.TLSPrivateKeyKey: keyPEM,
+	}
+	if previousCA != nil {
+		renewed[secretKeyPreviousCACert] = previousCA
+	}
+	return renewed, nil
+}
+
+// writeCertFiles writes the serving certificate of the given Secret to CertDir, unless the files are up to date.
+// The webhook server reloads the certificate once the files change.
+func (r *WebhookCertRotator) writeCertFiles(secret *corev1.Secret) error {
+	if err := os.MkdirAll(r.CertDir, 0o700); err != nil {
+		return err
+	}
+	for _, key := range []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey} {
+		path := filepath.Join(r.CertDir, key)
+		existing, err := os.ReadFile(path)
+		if err == nil && bytes.Equal(existing, secret.Data[key]) {
+			continue
+		}
+		if err := os.WriteFile(path, secret.Data[key], 0o600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// injectWebhookConfiguration sets the given CA bundle on all webhooks of the ValidatingWebhookConfiguration, if it exists.
+func (r *WebhookCertRotator) injectWebhookConfiguration(ctx context.Context, caBundle []byte) error {
+	if r.ValidatingWebhookConfiguration == "" {
+		return nil
+	}
+	cfg := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+	err := r.Client.Get(ctx, types.NamespacedName{Name: r.ValidatingWebhookConfiguration}, cfg)
+	if apierrors.IsNotFound(err) {
+		r.Log.V(1).Info("ValidatingWebhookConfiguration not found, skipping CA injection", "name", r.ValidatingWebhookConfiguration)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	patch := client.MergeFrom(cfg.DeepCopy())
+	changed := false
+	for i := range cfg.Webhooks {
+		if !bytes.Equal(cfg.Webhooks[i].ClientConfig.CABundle, caBundle) {
+			cfg.Webhooks[i].ClientConfig.CABundle = caBundle
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return r.Client.Patch(ctx, cfg, patch)
+}
+
+// injectCustomResourceDefinition sets the given CA bundle on the conversion webhook of the given CustomResourceDefinition,
+// if it exists and uses a conversion webhook.
+func (r *WebhookCertRotator) injectCustomResourceDefinition(ctx context.Context, name string, caBundle []byte) error {
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	err := r.Client.Get(ctx, types.NamespacedName{Name: name}, crd)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	conversion := crd.Spec.Conversion
+	if conversion == nil || conversion.Strategy != apiextensionsv1.WebhookConverter ||
+		conversion.Webhook == nil || conversion.Webhook.ClientConfig == nil ||
+		bytes.Equal(conversion.Webhook.ClientConfig.CABundle, caBundle) {
+		return nil
+	}
+	patch := client.MergeFrom(crd.DeepCopy())
+	conversion.Webhook.ClientConfig.CABundle = caBundle
+	return r.Client.Patch(ctx, crd, patch)
+}
+
+func (r *WebhookCertRotator) currentTime() time.Time {
+	if r.now != nil {
+		return r.now()
+	}
+	return time.Now()
+}
+
+// generateCA returns a new self-signed CA certificate and its key.
+func generateCA(now time.Time) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	template, err := certificateTemplate(now, caValidity)
+	if err != nil {
+		return nil, nil, err
+	}
+	template.Subject = pkix.Name{CommonName: "espejo-webhook-ca"}
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, nil, err
+	}
+	ca, err := x509.ParseCertificate(der)
+	return ca, key, err
+}
+
+// generateServingCert returns a new PEM encoded serving certificate for the given DNS names signed by the given CA, and its key.
+func generateServingCert(now time.Time, ca *x509.Certificate, caKey *ecdsa.PrivateKey, dnsNames []string) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	template, err := certificateTemplate(now, certValidity)
+	if err != nil {
+		return nil, nil, err
+	}
+	template.Subject = pkix.Name{CommonName: dnsNames[0]}
+	template.DNSNames = dnsNames
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, key.Public(), caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := encodeKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), keyPEM, nil
+}
+
+// certificateTemplate returns a certificate template with a random serial number valid from now for the given duration.
+func certificateTemplate(now time.Time, validity time.Duration) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	return &x509.Certificate{
+		SerialNumber: serial,
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(validity),
+	}, nil
+}
+
+// encodeKey returns the given key PEM encoded.
+func encodeKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
+
+// unexpiredCert returns the given PEM encoded certificate, or nil if it cannot be parsed or has expired.
+func unexpiredCert(now time.Time, certPEM []byte) []byte {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil || now.After(cert.NotAfter) {
+		return nil
+	}
+	return certPEM
+}
+
+// parseKeyPair parses the given PEM encoded certificate and its ECDSA key.
+func parseKeyPair(certPEM, keyPEM []byte) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, ok := pair.PrivateKey.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, nil, errors.New("private key is not an ECDSA key")
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	return cert, key, err
+}
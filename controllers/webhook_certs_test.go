@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_WebhookCertRotator_RenewCerts(t *testing.T) {
+	issued := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	rotator := &WebhookCertRotator{DNSNames: []string{"espejo-webhook-service.espejo.svc"}, now: func() time.Time { return issued }}
+	initial, err := rotator.renewCerts(nil)
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		givenNow        time.Time
+		givenDNSNames   []string
+		expectRenewal   bool
+		expectCARenewal bool
+	}{
+		"GivenValidCerts_ThenKeepCerts": {
+			givenNow: issued.Add(24 * time.Hour),
+		},
+		"GivenServingCertExpiresSoon_ThenRenewServingCertOnly": {
+			givenNow:      issued.Add(certValidity - certRenewBefore + time.Hour),
+			expectRenewal: true,
+		},
+		"GivenOtherDNSNames_ThenRenewServingCertOnly": {
+			givenNow:      issued.Add(24 * time.Hour),
+			givenDNSNames: []string{"other.espejo.svc"},
+			expectRenewal: true,
+		},
+		"GivenCAExpiresSoon_ThenRenewCA": {
+			givenNow:        issued.Add(caValidity - caRenewBefore + time.Hour),
+			expectRenewal:   true,
+			expectCARenewal: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := &WebhookCertRotator{DNSNames: rotator.DNSNames, now: func() time.Time { return tt.givenNow }}
+			if tt.givenDNSNames != nil {
+				r.DNSNames = tt.givenDNSNames
+			}
+
+			renewed, err := r.renewCerts(initial)
+
+			require.NoError(t, err)
+			if !tt.expectRenewal {
+				assert.Nil(t, renewed)
+				return
+			}
+			require.NotNil(t, renewed)
+			assert.NotEqual(t, initial[corev1.TLSCertKey], renewed[corev1.TLSCertKey])
+			assert.Equal(t, tt.expectCARenewal, string(initial[secretKeyCACert]) != string(renewed[secretKeyCACert]))
+			if tt.expectCARenewal {
+				assert.Equal(t, initial[secretKeyCACert], renewed[secretKeyPreviousCACert], "previous CA")
+			} else {
+				assert.NotContains(t, renewed, secretKeyPreviousCACert)
+			}
+			ca, _, err := parseKeyPair(renewed[secretKeyCACert], renewed[secretKeyCAKey])
+			require.NoError(t, err)
+			cert, _, err := parseKeyPair(renewed[corev1.TLSCertKey], renewed[corev1.TLSPrivateKeyKey])
+			require.NoError(t, err)
+			assert.NoError(t, cert.CheckSignatureFrom(ca))
+			assert.NoError(t, cert.VerifyHostname(r.DNSNames[0]))
+		})
+	}
+}
+
+func Test_WebhookCertRotator_CABundle(t *testing.T) {
+	issued := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	initial, err := (&WebhookCertRotator{DNSNames: []string{"espejo-webhook-service.espejo.svc"}, now: func() time.Time { return issued }}).renewCerts(nil)
+	require.NoError(t, err)
+	renewedAt := issued.Add(caValidity - caRenewBefore + time.Hour)
+	// The serving certificate signed by the previous CA has been renewed shortly before the CA is renewed.
+	previous, err := (&WebhookCertRotator{DNSNames: []string{"espejo-webhook-service.espejo.svc"}, now: func() time.Time { return renewedAt.Add(-2 * time.Hour) }}).renewCerts(initial)
+	require.NoError(t, err)
+	require.Equal(t, initial[secretKeyCACert], previous[secretKeyCACert])
+	r := &WebhookCertRotator{DNSNames: []string{"espejo-webhook-service.espejo.svc"}, now: func() time.Time { return renewedAt }}
+	renewed, err := r.renewCerts(previous)
+	require.NoError(t, err)
+
+	bundle := r.caBundle(renewed)
+
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM(bundle))
+	for name, data := range map[string]map[string][]byte{"previous": previous, "renewed": renewed} {
+		cert, _, err := parseKeyPair(data[corev1.TLSCertKey], data[corev1.TLSPrivateKeyKey])
+		require.NoError(t, err)
+		_, err = cert.Verify(x509.VerifyOptions{Roots: pool, CurrentTime: renewedAt})
+		assert.NoError(t, err, name+" serving certificate")
+	}
+
+	r.now = func() time.Time { return issued.Add(caValidity + time.Hour) }
+	assert.Equal(t, renewed[secretKeyCACert], r.caBundle(renewed), "expired previous CA")
+	cleaned, err := r.renewCerts(renewed)
+	require.NoError(t, err)
+	assert.NotContains(t, cleaned, secretKeyPreviousCACert)
+}
+
+func Test_WebhookCertRotator_WriteCertFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "serving-certs")
+	r := &WebhookCertRotator{CertDir: dir}
+	secret := &corev1.Secret{Data: map[string][]byte{
+		corev1.TLSCertKey:       []byte("cert"),
+		corev1.TLSPrivateKeyKey: []byte("key"),
+		secretKeyCAKey:          []byte("ca key"),
+	}}
+
+	require.NoError(t, r.writeCertFiles(secret))
+
+	cert, err := os.ReadFile(filepath.Join(dir, corev1.TLSCertKey))
+	require.NoError(t, err)
+	assert.Equal(t, "cert", string(cert))
+	key, err := os.ReadFile(filepath.Join(dir, corev1.TLSPrivateKeyKey))
+	require.NoError(t, err)
+	assert.Equal(t, "key", string(key))
+	assert.NoFileExists(t, filepath.Join(dir, secretKeyCAKey))
+}
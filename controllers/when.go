@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// whenVariable is the name of the variable holding the target namespace in the when expressions of the sync items.
+// "namespace" is a reserved identifier in CEL, so the name follows the ValidatingAdmissionPolicies of Kubernetes.
+const whenVariable = "namespaceObject"
+
+// compileWhen compiles the given CEL expression of a sync item. The expression must evaluate to a bool.
+func compileWhen(expression string) (cel.Program, error) {
+	env, err := cel.NewEnv(cel.Variable(whenVariable, cel.MapType(cel.StringType, cel.DynType)))
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := env.Compile(expression)
+	if issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if outputType := ast.OutputType(); !outputType.IsExactType(cel.BoolType) && !outputType.IsExactType(cel.DynType) {
+		return nil, fmt.Errorf("expression must evaluate to a bool, not %s", outputType)
+	}
+	return env.Program(ast)
+}
+
+// evaluateWhen returns whether the sync item with the given index is synced into the given target namespace according
+// to its when expression. Items without an expression are synced into all targeted namespaces.
+func (rc *ReconciliationContext) evaluateWhen(index int, targetNamespace corev1.Namespace) (bool, error) {
+	if index >= len(rc.conditions) || rc.conditions[index] == nil {
+		return true, nil
+	}
+	namespace, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&targetNamespace)
+	if err != nil {
+		return false, err
+	}
+	// Labels and annotations are always present, so that expressions can test for keys without checking the maps first.
+	metadata, _ := namespace["metadata"].(map[string]interface{})
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+		namespace["metadata"] = metadata
+	}
+	for _, field := range []string{"labels", "annotations"} {
+		if _, found := metadata[field]; !found {
+			metadata[field] = map[string]interface{}{}
+		}
+	}
+
+	out, _, err := rc.conditions[index].Eval(map[string]interface{}{whenVariable: namespace})
+	if err != nil {
+		return false, fmt.Errorf("%w: when: %w", errRenderFailed, err)
+	}
+	selected, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("%w: when: expression evaluates to %s instead of a bool", errRenderFailed, out.Type().TypeName())
+	}
+	return selected, nil
+}
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
+)
+
+func Test_ReconciliationContext_IsSelected(t *testing.T) {
+	prod := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "project-prod", Labels: map[string]string{"tier": "prod"}}}
+	unlabeled := namespaceFromString("project-dev")
+
+	tests := map[string]struct {
+		givenWhen        string
+		givenNamespace   corev1.Namespace
+		expectedSelected bool
+		expectedErr      string
+	}{
+		"GivenNoExpression_ThenSelect": {
+			givenNamespace:   unlabeled,
+			expectedSelected: true,
+		},
+		"GivenMatchingLabel_ThenSelect": {
+			givenWhen:        `namespaceObject.metadata.labels['tier'] == 'prod'`,
+			givenNamespace:   prod,
+			expectedSelected: true,
+		},
+		"GivenNamespaceWithoutLabels_WhenTestingKey_ThenDoNotSelect": {
+			givenWhen:        `'tier' in namespaceObject.metadata.labels && namespaceObject.metadata.labels['tier'] == 'prod'`,
+			givenNamespace:   unlabeled,
+			expectedSelected: false,
+		},
+		"GivenName_ThenSelectByName": {
+			givenWhen:        `namespaceObject.metadata.name.endsWith('-dev')`,
+			givenNamespace:   unlabeled,
+			expectedSelected: true,
+		},
+		"GivenMissingLabel_ThenReturnError": {
+			givenWhen:      `namespaceObject.metadata.labels['tier'] == 'prod'`,
+			givenNamespace: unlabeled,
+			expectedErr:    "no such key: tier",
+		},
+		"GivenNonBoolResult_ThenReturnError": {
+			givenWhen:      `namespaceObject.metadata.labels['tier']`,
+			givenNamespace: prod,
+			expectedErr:    "expression evaluates to string instead of a bool",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			rc := &ReconciliationContext{cfg: &syncv1beta1.SyncConfig{Spec: syncv1beta1.SyncConfigSpec{
+				NamespaceSelector: &syncv1beta1.NamespaceSelector{MatchNames: []string{".*"}},
+				SyncItems:         []syncv1beta1.SyncItem{{When: tt.givenWhen}},
+			}}}
+			require.NoError(t, rc.validateSpec())
+
+			selected, err := rc.isSelected(0, tt.givenNamespace)
+
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				assert.ErrorIs(t, err, errRenderFailed)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedSelected, selected)
+		})
+	}
+}
+
+func Test_CompileWhen_GivenInvalidExpression_ThenReturnError(t *testing.T) {
+	tests := map[string]string{
+		"GivenSyntaxError":     `namespaceObject.metadata.labels[`,
+		"GivenUnknownVariable": `ns.metadata.name == 'default'`,
+		"GivenNonBoolType":     `namespaceObject.metadata.name.size()`,
+	}
+	for name, expression := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := compileWhen(expression)
+
+			assert.Error(t, err)
+		})
+	}
+}
@@ -17,3 +17,11 @@ DEBUG_DETIK="true"
 	apply definitions/syncconfig
 	try "at most 10 times every 1s to get configmap named 'espejo-e2e-test-data' and verify that '.data.KEY' is 'espejo-system'"
 }
+
+@test "Given a v1alpha1 SyncConfig manifest, When creating it, Then expect it to be converted and synced" {
+	given_running_operator
+
+	apply definitions/syncconfig-v1alpha1
+	try "at most 10 times every 1s to get syncconfig named 'espejo-espejo-e2e-test-v1alpha1' and verify that '.spec.syncItems[0].manifest.metadata.name' is 'espejo-e2e-test-v1alpha1'"
+	try "at most 10 times every 1s to get configmap named 'espejo-e2e-test-v1alpha1' and verify that '.data.KEY' is 'espejo-system'"
+}
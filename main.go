@@ -1,27 +1,35 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"go.uber.org/zap/zapcore"
 
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	"github.com/knadh/koanf"
 	"github.com/knadh/koanf/providers/posflag"
 	flag "github.com/spf13/pflag"
 
 	syncv1alpha1 "github.com/vshn/espejo/api/v1alpha1"
+	syncv1beta1 "github.com/vshn/espejo/api/v1beta1"
 	"github.com/vshn/espejo/controllers"
 	// +kubebuilder:scaffold:imports
 )
@@ -35,38 +43,84 @@ var (
 	scheme        = runtime.NewScheme()
 	setupLog      = ctrl.Log.WithName("setup")
 	koanfInstance = koanf.New(".")
-	config        = Configuration{
-		LeaderElection:    false,
-		MetricsAddr:       ":8080",
-		ReconcileInterval: "10s",
+	// commands are the subcommands that run instead of the operator.
+	commands = map[string]func(args []string) int{
+		"render":          renderCommand,
+		"diff":            diffCommand,
+		"validate":        validateCommand,
+		"migrate-storage": migrateStorageCommand,
 	}
+	config = Configuration{
+		LeaderElection:       false,
+		MetricsAddr:          ":8080",
+		ReconcileInterval:    "10s",
+		WatchSyncItems:       true,
+		MetricsNamespaces:    true,
+		WebhookCertSecret:    "espejo-webhook-server-cert",
+		WebhookService:       "espejo-webhook-service",
+		WebhookConfiguration: "espejo-validating-webhook-configuration",
+	}
+)
+
+const (
+	// namespaceEventQPS and namespaceEventBurst limit the rate of events emitted in target namespaces.
+	namespaceEventQPS   = 5
+	namespaceEventBurst = 100
+
+	// webhookCertDir is the directory the webhook server reads its serving certificate from.
+	webhookCertDir = "/tmp/k8s-webhook-server/serving-certs"
+	// webhookCertCheckInterval is the interval in which generated webhook certificates are checked for renewal.
+	webhookCertCheckInterval = time.Hour
+	// serviceAccountNamespaceFile contains the namespace espejo runs in.
+	serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
 )
 
 type (
 	// Configuration holds all the operator-wide configurable settings.
 	Configuration struct {
-		LeaderElection    bool   `koanf:"enable-leader-election"`
-		MetricsAddr       string `koanf:"metrics-addr"`
-		ReconcileInterval string `koanf:"reconcile-interval"`
-		Debug             bool   `koanf:"verbose"`
+		LeaderElection       bool   `koanf:"enable-leader-election"`
+		MetricsAddr          string `koanf:"metrics-addr"`
+		MetricsNamespaces    bool   `koanf:"metrics-namespace-label"`
+		ReconcileInterval    string `koanf:"reconcile-interval"`
+		WatchSyncItems       bool   `koanf:"watch-sync-items"`
+		NamespaceEvents      bool   `koanf:"namespace-events"`
+		DryRun               bool   `koanf:"dry-run"`
+		ClusterName          string `koanf:"cluster-name"`
+		EnableWebhooks       bool   `koanf:"enable-webhooks"`
+		WebhookCertRotation  bool   `koanf:"webhook-cert-rotation"`
+		WebhookCertSecret    string `koanf:"webhook-cert-secret"`
+		WebhookService       string `koanf:"webhook-service"`
+		WebhookConfiguration string `koanf:"webhook-configuration"`
+		Debug                bool   `koanf:"verbose"`
 	}
 )
 
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
 	utilruntime.Must(syncv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(syncv1beta1.AddToScheme(scheme))
 	// +kubebuilder:scaffold:scheme
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			os.Exit(command(os.Args[2:]))
+		}
+	}
 	loadConfig()
 	setupLogger()
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	restConfig := ctrl.GetConfigOrDie()
+	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
 		Scheme: scheme,
 		Metrics: server.Options{
 			BindAddress: config.MetricsAddr,
 		},
+		WebhookServer: webhook.NewServer(webhook.Options{
+			CertDir: webhookCertDir,
+		}),
 		LeaderElection:   config.LeaderElection,
 		LeaderElectionID: "bd39f6a0.appuio.ch",
 		// Limit the manager to only watch the given namespace
@@ -90,11 +144,34 @@ func main() {
 
 	setupLog.V(1).Info("Configuration from flags", "config", config)
 
+	var watcher *controllers.ItemWatcher
+	if config.WatchSyncItems {
+		watcher, err = controllers.NewItemWatcher(mgr, ctrl.Log.WithName("controllers").WithName("ItemWatcher"))
+		if err != nil {
+			setupLog.Error(err, "unable to create watcher for synced objects")
+			os.Exit(1)
+		}
+	}
+
+	recorder := mgr.GetEventRecorderFor("espejo")
+	var namespaceRecorder record.EventRecorder
+	if config.NamespaceEvents {
+		namespaceRecorder = controllers.NewRateLimitedRecorder(recorder, namespaceEventQPS, namespaceEventBurst)
+	}
+
+	renderedTargets := controllers.NewRenderedTargets()
 	supplier := func() *controllers.SyncConfigReconciler {
 		return &controllers.SyncConfigReconciler{
-			Client: mgr.GetClient(),
-			Log:    ctrl.Log.WithName("controllers").WithName("Namespace"),
-			Scheme: mgr.GetScheme(),
+			Client:                  mgr.GetClient(),
+			Log:                     ctrl.Log.WithName("controllers").WithName("Namespace"),
+			Scheme:                  mgr.GetScheme(),
+			Watcher:                 watcher,
+			Recorder:                recorder,
+			NamespaceRecorder:       namespaceRecorder,
+			MetricsWithoutNamespace: !config.MetricsNamespaces,
+			DryRun:                  config.DryRun,
+			ClusterName:             config.ClusterName,
+			RenderedTargets:         renderedTargets,
 		}
 	}
 	mainScr := supplier()
@@ -112,10 +189,24 @@ func main() {
 		Log:                     ctrl.Log.WithName("controllers").WithName("Namespace"),
 		Scheme:                  mgr.GetScheme(),
 		NewSyncConfigReconciler: supplier,
+		Watcher:                 watcher,
+		Recorder:                recorder,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Namespace")
 		os.Exit(1)
 	}
+	if config.EnableWebhooks {
+		if err = (&controllers.SyncConfigValidator{
+			RESTMapper: mgr.GetRESTMapper(),
+			Scheme:     mgr.GetScheme(),
+		}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "SyncConfig")
+			os.Exit(1)
+		}
+		if config.WebhookCertRotation {
+			setupWebhookCertRotation(mgr, restConfig)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	setupLog.WithValues("version", version, "date", date, "commit", commit).Info("starting manager")
@@ -125,6 +216,55 @@ func main() {
 	}
 }
 
+// setupWebhookCertRotation generates the webhook serving certificate before the manager starts and adds a runnable
+// renewing it to the given manager.
+func setupWebhookCertRotation(mgr ctrl.Manager, restConfig *rest.Config) {
+	c, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		setupLog.Error(err, "unable to create client for webhook certificates")
+		os.Exit(1)
+	}
+	namespace, err := getOperatorNamespace()
+	if err != nil {
+		setupLog.Error(err, "unable to set up webhook certificates")
+		os.Exit(1)
+	}
+	rotator := &controllers.WebhookCertRotator{
+		Client: c,
+		Log:    ctrl.Log.WithName("webhook").WithName("CertRotator"),
+		Secret: types.NamespacedName{Namespace: namespace, Name: config.WebhookCertSecret},
+		DNSNames: []string{
+			fmt.Sprintf("%s.%s.svc", config.WebhookService, namespace),
+			fmt.Sprintf("%s.%s.svc.cluster.local", config.WebhookService, namespace),
+		},
+		CertDir:                        webhookCertDir,
+		ValidatingWebhookConfiguration: config.WebhookConfiguration,
+		CustomResourceDefinitions:      []string{"syncconfigs." + syncv1beta1.GroupVersion.Group},
+		Interval:                       webhookCertCheckInterval,
+	}
+	if err := rotator.EnsureCerts(context.Background()); err != nil {
+		setupLog.Error(err, "unable to generate webhook certificates")
+		os.Exit(1)
+	}
+	if err := mgr.Add(rotator); err != nil {
+		setupLog.Error(err, "unable to add webhook certificate rotator")
+		os.Exit(1)
+	}
+}
+
+// getOperatorNamespace returns the Namespace the operator is running in.
+// Outside of a cluster, the watched Namespace is used. It returns an error if neither is available.
+func getOperatorNamespace() (string, error) {
+	raw, err := os.ReadFile(serviceAccountNamespaceFile)
+	if namespace := strings.TrimSpace(string(raw)); err == nil && namespace != "" {
+		return namespace, nil
+	}
+	if namespace := getWatchNamespace(); namespace != "" {
+		return namespace, nil
+	}
+	return "", fmt.Errorf("cannot determine the namespace of the operator: %s is not readable and WATCH_NAMESPACE is not set", serviceAccountNamespaceFile)
+}
+
 // getWatchNamespace returns the Namespace the operator should be watching for changes
 // An empty value means the operator is running with cluster scope.
 func getWatchNamespace() string {
@@ -135,13 +275,31 @@ func getWatchNamespace() string {
 func loadConfig() {
 	f := flag.NewFlagSet("config", flag.ContinueOnError)
 	f.String("metrics-addr", config.MetricsAddr, "The address the metric endpoint binds to.")
+	f.Bool("metrics-namespace-label", config.MetricsNamespaces, "Label the metrics of synced objects with their namespace. "+
+		"Disable on clusters with many namespaces to limit the cardinality of the metrics.")
 	f.Bool("enable-leader-election", config.LeaderElection, "Enable leader election for controller manager. "+
 		"Enabling this will ensure there is only one active controller manager.")
 	f.String("reconcile-interval", config.ReconcileInterval, "The interval of which SyncConfigs get reconciled.")
+	f.Bool("watch-sync-items", config.WatchSyncItems, "Watch synced objects and correct drift as soon as they are modified or deleted.")
+	f.Bool("namespace-events", config.NamespaceEvents, "Emit events about synced objects in the target namespaces.")
+	f.Bool("dry-run", config.DryRun, "Reconcile all SyncConfigs in dry-run mode without creating, changing or deleting any objects.")
+	f.String("cluster-name", config.ClusterName, "Name of the cluster, the value of the ${CLUSTER_NAME} placeholder in sync items.")
+	f.Bool("enable-webhooks", config.EnableWebhooks, "Serve the validating admission webhook and the conversion webhook for SyncConfigs. "+
+		"Requires a serving certificate in /tmp/k8s-webhook-server/serving-certs.")
+	f.Bool("webhook-cert-rotation", config.WebhookCertRotation, "Generate and renew a self-signed serving certificate for the webhook "+
+		"and inject its CA into the webhook configurations, instead of relying on cert-manager.")
+	f.String("webhook-cert-secret", config.WebhookCertSecret, "Name of the Secret in which generated webhook certificates are stored.")
+	f.String("webhook-service", config.WebhookService, "Name of the Service of the webhook, which the generated certificate is valid for.")
+	f.String("webhook-configuration", config.WebhookConfiguration, "Name of the ValidatingWebhookConfiguration to inject the generated CA into.")
 	f.BoolP("verbose", "v", config.Debug, "Enable debug mode")
 	f.Usage = func() {
 		fmt.Println("Usage of Espejo:")
 		fmt.Print(f.FlagUsages())
+		fmt.Println("\nSubcommands:")
+		fmt.Println("  render           Render SyncConfigs for the given namespaces without a cluster")
+		fmt.Println("  diff             Show the changes SyncConfigs would make in the cluster of the current kubeconfig context")
+		fmt.Println("  validate         Validate SyncConfig manifests without a cluster")
+		fmt.Println("  migrate-storage  Store all SyncConfigs in the cluster of the current kubeconfig context as v1beta1")
 		os.Exit(0)
 	}
 	if err := f.Parse(os.Args[1:]); err != nil {